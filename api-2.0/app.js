@@ -59,6 +59,7 @@ app.use((req, res, next) => {
         } else {
             req.username = decoded.username;
             req.orgname = decoded.orgName;
+            req.permissions = decoded.permissions;
             logger.debug(util.format('Decoded from JWT token: username - %s, orgname - %s', decoded.username, decoded.orgName));
             return next();
         }
@@ -301,7 +302,7 @@ app.post('/channels/:channelName/chaincodes/:chaincodeName', async function (req
             return;
         }
 
-        let message = await invoke.invokeTransaction(channelName, chaincodeName, fcn, args, req.username, req.orgname, transient);
+        let message = await invoke.invokeTransaction(channelName, chaincodeName, fcn, args, req.username, req.orgname, req.permissions, transient);
         console.log(`message result is : ${message}`)
 
         const response_payload = {
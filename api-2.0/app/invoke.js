@@ -9,6 +9,36 @@ const util = require('util')
 const helper = require('./helper');
 const { blockListener, contractListener } = require('./Listeners');
 
+// toTransientMap encodes each entry of the request's transient data as a buffer, JSON encoding objects
+const toTransientMap = (transientData) => {
+    const transientMap = {};
+    for (const [key, value] of Object.entries(transientData || {})) {
+        transientMap[key] = Buffer.from(typeof value === 'string' ? value : JSON.stringify(value));
+    }
+    return transientMap;
+}
+
+// getTrustAnchorEndorsers returns the organizations that must endorse approving trust anchors: the
+// organization itself, the approving organization and the one that approved its current anchors
+const getTrustAnchorEndorsers = async (contract, mspID, approverMSP) => {
+    let current = {};
+    try {
+        current = JSON.parse((await contract.evaluateTransaction("QueryMSPTrustAnchors", mspID)).toString());
+    } catch (error) {
+        // No trust anchors have been approved yet
+    }
+    return [...new Set([mspID, approverMSP, current.approvedMSP].filter(Boolean))];
+}
+
+// getTransferEndorsers returns the organizations that must endorse accepting a case transfer: the
+// case's current owner and supervisor, and the organization owning the receiving court
+const getTransferEndorsers = async (contract, caseID, transferID) => {
+    const record = JSON.parse((await contract.evaluateTransaction("QueryLegalRecord", caseID)).toString());
+    const transfers = JSON.parse((await contract.evaluateTransaction("GetCaseTransfers", caseID)).toString()) || [];
+    const transfer = transfers.find((t) => t.transferID === transferID) || {};
+    return [...new Set([record.ownerMSP, record.supervisorMSP, transfer.toMSP].filter(Boolean))];
+}
+
 const invokeTransaction = async (channelName, chaincodeName, fcn, args, username, org_name, permissions, transientData) => {
     try {
         const ccp = await helper.getCCP(org_name);
@@ -61,21 +91,214 @@ const invokeTransaction = async (channelName, chaincodeName, fcn, args, username
         switch (fcn) {
             case "CreateUser":
                 result = await contract.submitTransaction(fcn, args[0]);
-                result = {txid: result.toString()};
+                result = JSON.parse(result.toString());
                 break;
             case "UpdateUser":
                 result = await contract.submitTransaction(fcn, args[0], args[1]);
-                result = {txid: result.toString()};
+                result = JSON.parse(result.toString());
                 break;
             // Legal record operations
+            // Private fields of non-public records are only accepted in the transient map
             case "CreateLegalRecord":
+                result = await contract.createTransaction(fcn)
+                    .setTransient(toTransientMap(transientData))
+                    .submit(args[0]);
+                result = JSON.parse(result.toString());
+                break;
+            case "UpdateLegalRecord":
+                result = await contract.createTransaction(fcn)
+                    .setTransient(toTransientMap(transientData))
+                    .submit(args[0], args[1]);
+                result = JSON.parse(result.toString());
+                break;
+            // Access request operations
+            case "RequestRecordAccess":
+                result = await contract.submitTransaction(fcn, args[0], args[1]);
+                result = {txid: result.toString()};
+                break;
+            case "ApproveAccessRequest":
+            case "DenyAccessRequest":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                message = `${fcn} completed for request ${args[1]}`;
+                break;
+            // Emergency access operations
+            case "EmergencyAccessLegalRecord":
+                result = await contract.submitTransaction(fcn, args[0], args[1]);
+                result = JSON.parse(result.toString());
+                break;
+            case "ReviewEmergencyAccess":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                message = `${fcn} completed for access ${args[1]}`;
+                break;
+            // Audited read operations
+            case "ReadLegalRecordAudited":
+                result = await contract.submitTransaction(fcn, args[0], args[1]);
+                result = JSON.parse(result.toString());
+                break;
+            // Court registry operations
+            case "CreateCourt":
                 result = await contract.submitTransaction(fcn, args[0]);
                 result = {txid: result.toString()};
                 break;
-            case "UpdateLegalRecord":
+            case "UpdateCourt":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Court ${args[0]} updated`;
+                break;
+            // Judge registry and assignment operations
+            case "CreateJudge":
+                result = await contract.submitTransaction(fcn, args[0]);
+                result = {txid: result.toString()};
+                break;
+            case "UpdateJudge":
+            case "AssignJudge":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `${fcn} completed for ${args[0]}`;
+                break;
+            case "UnassignJudge":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2] || "");
+                message = `${fcn} completed for ${args[0]}`;
+                break;
+            case "RecuseJudge":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                message = `Judge ${args[1]} recused from ${args[0]}`;
+                break;
+            case "AutoAssignJudge":
+                result = await contract.submitTransaction(fcn, args[0]);
+                result = JSON.parse(result.toString());
+                break;
+            case "AddParticipant":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Participant added to ${args[0]}`;
+                break;
+            case "RemoveParticipant":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Participant ${args[1]} removed from ${args[0]}`;
+                break;
+            case "RegisterLawyer":
+                result = await contract.submitTransaction(fcn, args[0]);
+                result = {txid: result.toString()};
+                break;
+            case "UpdateLawyerStatus":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2], args[3]);
+                message = `Lawyer ${args[1]} in ${args[0]} is now ${args[2]}`;
+                break;
+            case "ScheduleHearing":
                 result = await contract.submitTransaction(fcn, args[0], args[1]);
                 result = {txid: result.toString()};
                 break;
+            case "RecordHearingOutcome":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                message = `Outcome recorded for hearing ${args[1]}`;
+                break;
+            case "CancelHearing":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                message = `Hearing ${args[1]} cancelled`;
+                break;
+            case "AddDocketEntry":
+                result = await contract.submitTransaction(fcn, args[0], args[1]);
+                result = JSON.parse(result.toString());
+                break;
+            case "IssueOrder":
+                result = await contract.submitTransaction(fcn, args[0], args[1]);
+                result = JSON.parse(result.toString());
+                break;
+            case "ProposeMSPTrustAnchors":
+                await contract.submitTransaction(fcn, args[0]);
+                message = `Trust anchors proposed for ${org_name}`;
+                break;
+            case "ApproveMSPTrustAnchors":
+                await contract.createTransaction(fcn)
+                    .setEndorsingOrganizations(...await getTrustAnchorEndorsers(contract, args[0], `${org_name}MSP`))
+                    .submit(args[0]);
+                message = `Trust anchors of ${args[0]} approved`;
+                break;
+            case "SignJudgment":
+                result = await contract.submitTransaction(fcn, args[0], args[1], args[2], args[3], args[4]);
+                result = JSON.parse(result.toString());
+                break;
+            case "LinkCases":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                message = `Linked ${args[0]} ${args[1]} ${args[2]}`;
+                break;
+            case "ConsolidateCases":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Cases ${args[1]} consolidated into ${args[0]}`;
+                break;
+            case "SeverCase":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Case ${args[1]} severed from ${args[0]}`;
+                break;
+            case "InitiateCaseTransfer":
+                result = await contract.submitTransaction(fcn, args[0], args[1], args[2] || "", args[3]);
+                result = {txid: result.toString()};
+                break;
+            case "AcceptCaseTransfer":
+                await contract.createTransaction(fcn)
+                    .setEndorsingOrganizations(...await getTransferEndorsers(contract, args[0], args[1]))
+                    .setTransient(toTransientMap(transientData))
+                    .submit(args[0], args[1]);
+                message = `Transfer ${args[1]} of ${args[0]} accepted`;
+                break;
+            case "RejectCaseTransfer":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                message = `Transfer ${args[1]} of ${args[0]} rejected`;
+                break;
+            case "GenerateCaseNumber":
+                result = await contract.submitTransaction(fcn, args[0], args[1]);
+                result = {caseNumber: result.toString()};
+                break;
+            case "SetDeadlineRule":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Deadline rule saved for court ${args[0]}`;
+                break;
+            case "RemoveDeadlineRule":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Deadline rule ${args[1]} removed from court ${args[0]}`;
+                break;
+            case "AddCourtHoliday":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2] || "");
+                message = `Holiday ${args[1]} added for court ${args[0]}`;
+                break;
+            case "RemoveCourtHoliday":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Holiday ${args[1]} removed for court ${args[0]}`;
+                break;
+            case "TriggerDeadlines":
+                result = await contract.submitTransaction(fcn, args[0], args[1], args[2] || "");
+                result = JSON.parse(result.toString());
+                break;
+            case "CompleteDeadline":
+                await contract.submitTransaction(fcn, args[0], args[1]);
+                message = `Deadline ${args[1]} of ${args[0]} met`;
+                break;
+            case "CreateFiling":
+                result = await contract.submitTransaction(fcn, args[0], args[1]);
+                result = JSON.parse(result.toString());
+                break;
+            case "UpdateFiling":
+                result = await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                result = JSON.parse(result.toString());
+                break;
+            case "SubmitFiling":
+                result = await contract.submitTransaction(fcn, args[0], args[1]);
+                result = JSON.parse(result.toString());
+                break;
+            case "AcceptFiling":
+                result = await contract.submitTransaction(fcn, args[0], args[1], args[2] || "");
+                result = JSON.parse(result.toString());
+                break;
+            case "RejectFiling":
+                result = await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                result = JSON.parse(result.toString());
+                break;
+            case "PlaceLegalHold":
+                result = await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                result = {txid: result.toString()};
+                break;
+            case "ReleaseLegalHold":
+                await contract.submitTransaction(fcn, args[0], args[1], args[2]);
+                message = `Legal hold on ${args[0]} released`;
+                break;
             default:
                 break;
         }
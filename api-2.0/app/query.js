@@ -53,14 +53,107 @@ const query = async (channelName, chaincodeName, args, fcn, username, org_name,
                 console.log("=============")
                 result = await contract.evaluateTransaction(fcn);
                 break;
+            // Reads of non-public records are written to the access log, so they are submitted
             case "QueryLegalRecord":
                 console.log("=============")
-                result = await contract.evaluateTransaction(fcn, args[0], args[1]);
+                result = await contract.submitTransaction(fcn, args[0]);
                 break;
             case "QueryAllLegalRecords":
                 console.log("=============")
+                result = await contract.submitTransaction(fcn);
+                break;
+            case "QueryPendingAccessRequests":
+                result = await contract.evaluateTransaction(fcn, args[0] || "");
+                break;
+            case "QueryEmergencyAccesses":
+                result = await contract.evaluateTransaction(fcn, args[0] || "");
+                break;
+            case "QueryCasesFlaggedForReview":
                 result = await contract.evaluateTransaction(fcn);
                 break;
+            case "GetRecordAccessLog":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "QueryCourt":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "QueryAllCourts":
+                result = await contract.evaluateTransaction(fcn);
+                break;
+            case "QueryJudge":
+            case "QueryJudgesByCourt":
+            case "GetJudgeAssignmentHistory":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "GetJudgeDraws":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "VerifyJudgeDraw":
+                result = await contract.evaluateTransaction(fcn, args[0], args[1]);
+                break;
+            case "QueryLawyer":
+                result = await contract.evaluateTransaction(fcn, args[0], args[1]);
+                break;
+            case "QueryLawyersByJurisdiction":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "QueryHearings":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "GetJudgeCalendar":
+                result = await contract.evaluateTransaction(fcn, args[0], args[1], args[2]);
+                break;
+            case "GetCourtroomCalendar":
+                result = await contract.evaluateTransaction(fcn, args[0], args[1], args[2], args[3]);
+                break;
+            case "GetDocket":
+                result = await contract.evaluateTransaction(fcn, args[0], args[1] || "0", args[2] || "");
+                break;
+            case "QueryOrder":
+                result = await contract.evaluateTransaction(fcn, args[0], args[1]);
+                break;
+            case "QueryOrders":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "QueryMSPTrustAnchors":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "GetCaseRelationships":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "GetCaseLineage":
+                result = await contract.evaluateTransaction(fcn, args[0], args[1] || "0");
+                break;
+            case "ResolveCaseID":
+            case "GetCaseConsolidations":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "GetCaseTransfers":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "GetDeadlineRules":
+            case "GetCourtHolidays":
+            case "GetCaseDeadlines":
+            case "GetJudgeDeadlines":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "QueryFilings":
+            case "QueryCaseDocuments":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "VerifyReceipt":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "QueryLegalRecordHash":
+            case "QueryUserHash":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
+            case "QueryDocumentHash":
+                result = await contract.evaluateTransaction(fcn, args[0], args[1]);
+                break;
+            case "GetLegalHolds":
+                result = await contract.evaluateTransaction(fcn, args[0]);
+                break;
             default:
                 break;
         }
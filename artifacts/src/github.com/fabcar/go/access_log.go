@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const recordAccessLogObjectType = "recordAccessLog"
+
+// RecordAccessLogEntry is one audited read of a legal record. Each read is written under its own
+// key (case, timestamp, tx ID) so concurrent reads of the same case never MVCC-conflict.
+type RecordAccessLogEntry struct {
+	EntryID   string `json:"entryID"`
+	CaseID    string `json:"caseID"`
+	Reader    string `json:"reader"`
+	ReaderMSP string `json:"readerMSP"`
+	Purpose   string `json:"purpose"`
+	View      string `json:"view,omitempty" metadata:"view,optional"`
+	ReadAt    string `json:"readAt"`
+}
+
+// ReadLegalRecordAudited returns a legal record the caller has access to and appends the read to the
+// case's access log. It must be submitted (not evaluated) for the log entry to be committed.
+func (s *SmartContract) ReadLegalRecordAudited(ctx contractapi.TransactionContextInterface, caseID string, purpose string) (*LegalRecord, error) {
+	if len(purpose) == 0 {
+		return nil, fmt.Errorf("Please pass the purpose of the read")
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	recordView, err := getRecordView(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+	if recordView == RecordViewSummary {
+		return nil, fmt.Errorf("Access Denied: You do not have access to this legal record.")
+	}
+
+	err = logRecordRead(ctx, caseID, purpose, recordView)
+	if err != nil {
+		return nil, err
+	}
+
+	fullRecord, err := withPrivateDetails(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	return redactLegalRecord(fullRecord, recordView), nil
+}
+
+// logRecordRead appends a read of a legal record by the calling identity, and the view it was given,
+// to the case's access log
+func logRecordRead(ctx contractapi.TransactionContextInterface, caseID string, purpose string, recordView string) error {
+	reader, err := getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	readerMSP, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("Failed to get client MSP ID. %s", err.Error())
+	}
+	readAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry := RecordAccessLogEntry{
+		EntryID:   ctx.GetStub().GetTxID(),
+		CaseID:    caseID,
+		Reader:    reader,
+		ReaderMSP: readerMSP,
+		Purpose:   purpose,
+		View:      recordView,
+		ReadAt:    readAt,
+	}
+
+	entryKey, err := ctx.GetStub().CreateCompositeKey(recordAccessLogObjectType, []string{caseID, entry.ReadAt, entry.EntryID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	entryAsBytes, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal access log entry. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(entryKey, entryAsBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to put access log entry. %s", err.Error())
+	}
+	return nil
+}
+
+// GetRecordAccessLog returns the audited reads of a legal record in chronological order
+func (s *SmartContract) GetRecordAccessLog(ctx contractapi.TransactionContextInterface, caseID string) ([]*RecordAccessLogEntry, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+	if len(caseID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct case id")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(recordAccessLogObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var entries []*RecordAccessLogEntry
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var entry RecordAccessLogEntry
+		err = json.Unmarshal(queryResponse.Value, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal access log entry. %s", err.Error())
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
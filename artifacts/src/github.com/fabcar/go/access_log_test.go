@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func getRecordAccessLog(e *testEnv, caseID string) []*RecordAccessLogEntry {
+	e.t.Helper()
+	var entries []*RecordAccessLogEntry
+	payload := e.mustInvoke("GetRecordAccessLog", caseID)
+	if len(payload) == 0 {
+		return entries
+	}
+	err := json.Unmarshal([]byte(payload), &entries)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return entries
+}
+
+func TestReadLegalRecordAudited(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}, "usersWithAccess": []string{"alice"}}))
+
+	e.as("Org2MSP", "alice", "client")
+	e.mustFail("ReadLegalRecordAudited", "C1", "")
+	record := e.mustInvoke("ReadLegalRecordAudited", "C1", "discovery")
+	if jsonField(t, record, "courtID") != "CT1" {
+		t.Fatal(record)
+	}
+	e.as("Org2MSP", "mallory", "client")
+	e.mustFail("ReadLegalRecordAudited", "C1", "curiosity")
+
+	e.mustFail("GetRecordAccessLog", "C1")
+	e.as("Org1MSP", "admin1", "approver")
+	entries := getRecordAccessLog(e, "C1")
+	if len(entries) != 1 || entries[0].Reader != "alice" || entries[0].ReaderMSP != "Org2MSP" || entries[0].Purpose != "discovery" {
+		t.Fatalf("unexpected access log %+v", entries)
+	}
+}
+
+func TestQueryLegalRecordIsLogged(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}, "usersWithAccess": []string{"alice"}}))
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "P1", "courtID": "CT1", "judges": []string{}, "confidentiality": "PUBLIC"}))
+
+	// Privileged, granted and redacted reads of a non-public record are all logged
+	e.mustInvoke("QueryLegalRecord", "C1")
+	e.as("Org2MSP", "alice", "client")
+	e.mustInvoke("QueryLegalRecord", "C1")
+	e.as("Org2MSP", "mallory", "client")
+	e.mustInvoke("QueryLegalRecord", "C1")
+	e.mustInvoke("QueryLegalRecord", "P1")
+	e.mustFail("QueryLegalRecord", "C404")
+
+	e.as("Org1MSP", "admin1", "approver")
+	entries := getRecordAccessLog(e, "C1")
+	if len(entries) != 3 {
+		t.Fatalf("unexpected access log %+v", entries)
+	}
+	for i, reader := range []string{"admin1", "alice", "mallory"} {
+		if entries[i].Reader != reader || entries[i].Purpose != "QueryLegalRecord" {
+			t.Errorf("entry %d: %+v", i, entries[i])
+		}
+	}
+	if entries := getRecordAccessLog(e, "P1"); len(entries) != 0 {
+		t.Fatalf("reads of a public record were logged: %+v", entries)
+	}
+}
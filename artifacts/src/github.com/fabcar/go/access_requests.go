@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const accessRequestObjectType = "accessRequest"
+
+const (
+	AccessRequestPending  = "PENDING"
+	AccessRequestApproved = "APPROVED"
+	AccessRequestDenied   = "DENIED"
+)
+
+// AccessRequest is a user's request to be added to a legal record's UsersWithAccess
+type AccessRequest struct {
+	RequestID     string `json:"requestID"`
+	CaseID        string `json:"caseID"`
+	Requester     string `json:"requester"`
+	Justification string `json:"justification"`
+	Status        string `json:"status"`
+	RequestedAt   string `json:"requestedAt"`
+	DecidedBy     string `json:"decidedBy"`
+	DecidedAt     string `json:"decidedAt"`
+	DecisionNote  string `json:"decisionNote"`
+}
+
+// RequestRecordAccess records a pending request from the calling user to read a restricted legal record
+func (s *SmartContract) RequestRecordAccess(ctx contractapi.TransactionContextInterface, caseID string, justification string) (string, error) {
+	if len(caseID) == 0 {
+		return "", fmt.Errorf("Please pass the correct case id")
+	}
+	if len(justification) == 0 {
+		return "", fmt.Errorf("Please pass a justification for the access request")
+	}
+
+	requester, err := getClientName(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return "", err
+	}
+	if hasRecordAccess(ctx, legalRecord, requester) {
+		return "", fmt.Errorf("%s already has access to %s", requester, caseID)
+	}
+
+	pending, err := getAccessRequests(ctx, caseID, AccessRequestPending)
+	if err != nil {
+		return "", err
+	}
+	for _, request := range pending {
+		if request.Requester == requester {
+			return "", fmt.Errorf("Access request %s is already pending for %s", request.RequestID, requester)
+		}
+	}
+
+	requestedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	request := AccessRequest{
+		RequestID:     ctx.GetStub().GetTxID(),
+		CaseID:        caseID,
+		Requester:     requester,
+		Justification: justification,
+		Status:        AccessRequestPending,
+		RequestedAt:   requestedAt,
+	}
+
+	requestAsBytes, err := putAccessRequest(ctx, &request)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.GetStub().SetEvent("RequestRecordAccess", requestAsBytes)
+
+	return request.RequestID, nil
+}
+
+// ApproveAccessRequest grants the requester access to the legal record and closes the request
+func (s *SmartContract) ApproveAccessRequest(ctx contractapi.TransactionContextInterface, caseID string, requestID string, note string) error {
+	request, err := decideAccessRequest(ctx, caseID, requestID, AccessRequestApproved, note)
+	if err != nil {
+		return err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if !hasRecordAccess(ctx, legalRecord, request.Requester) {
+		legalRecord.UsersWithAccess = append(legalRecord.UsersWithAccess, request.Requester)
+		_, err = putLegalRecord(ctx, legalRecord)
+		if err != nil {
+			return err
+		}
+	}
+
+	requestAsBytes, err := putAccessRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("ApproveAccessRequest", requestAsBytes)
+}
+
+// DenyAccessRequest closes the request without granting access
+func (s *SmartContract) DenyAccessRequest(ctx contractapi.TransactionContextInterface, caseID string, requestID string, reason string) error {
+	if len(reason) == 0 {
+		return fmt.Errorf("Please pass a reason for denying the access request")
+	}
+
+	request, err := decideAccessRequest(ctx, caseID, requestID, AccessRequestDenied, reason)
+	if err != nil {
+		return err
+	}
+
+	requestAsBytes, err := putAccessRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("DenyAccessRequest", requestAsBytes)
+}
+
+// QueryPendingAccessRequests lists pending access requests for a case, or for all cases when caseID is empty
+func (s *SmartContract) QueryPendingAccessRequests(ctx contractapi.TransactionContextInterface, caseID string) ([]*AccessRequest, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+
+	return getAccessRequests(ctx, caseID, AccessRequestPending)
+}
+
+// decideAccessRequest loads a pending request and stamps the approver's decision on it
+func decideAccessRequest(ctx contractapi.TransactionContextInterface, caseID string, requestID string, status string, note string) (*AccessRequest, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := getAccessRequest(ctx, caseID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != AccessRequestPending {
+		return nil, fmt.Errorf("Access request %s is already %s", requestID, request.Status)
+	}
+
+	decidedBy, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decidedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = status
+	request.DecidedBy = decidedBy
+	request.DecidedAt = decidedAt
+	request.DecisionNote = note
+
+	return request, nil
+}
+
+func getAccessRequest(ctx contractapi.TransactionContextInterface, caseID string, requestID string) (*AccessRequest, error) {
+	requestKey, err := ctx.GetStub().CreateCompositeKey(accessRequestObjectType, []string{caseID, requestID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	requestAsBytes, err := ctx.GetStub().GetState(requestKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if requestAsBytes == nil {
+		return nil, fmt.Errorf("Access request %s does not exist for %s", requestID, caseID)
+	}
+
+	request := new(AccessRequest)
+	err = json.Unmarshal(requestAsBytes, request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal access request. %s", err.Error())
+	}
+	return request, nil
+}
+
+func putAccessRequest(ctx contractapi.TransactionContextInterface, request *AccessRequest) ([]byte, error) {
+	requestKey, err := ctx.GetStub().CreateCompositeKey(accessRequestObjectType, []string{request.CaseID, request.RequestID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	requestAsBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal access request. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(requestKey, requestAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put access request. %s", err.Error())
+	}
+	return requestAsBytes, nil
+}
+
+func getAccessRequests(ctx contractapi.TransactionContextInterface, caseID string, status string) ([]*AccessRequest, error) {
+	var keys []string
+	if len(caseID) > 0 {
+		keys = []string{caseID}
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(accessRequestObjectType, keys)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var requests []*AccessRequest
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var request AccessRequest
+		err = json.Unmarshal(queryResponse.Value, &request)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal access request. %s", err.Error())
+		}
+
+		if len(status) == 0 || request.Status == status {
+			requests = append(requests, &request)
+		}
+	}
+
+	return requests, nil
+}
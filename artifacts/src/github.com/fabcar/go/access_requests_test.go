@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newSealedRecordEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":          "C1",
+		"courtID":         "CT1",
+		"judges":          []string{},
+		"confidentiality": "SEALED",
+		"usersWithAccess": []string{"admin1"},
+	}))
+	return e
+}
+
+func getPendingAccessRequests(e *testEnv, caseID string) []*AccessRequest {
+	e.t.Helper()
+	e.as("Org1MSP", "admin1", "approver")
+	var requests []*AccessRequest
+	payload := e.mustInvoke("QueryPendingAccessRequests", caseID)
+	// An empty list is returned as an empty payload
+	if len(payload) == 0 {
+		return requests
+	}
+	err := json.Unmarshal([]byte(payload), &requests)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return requests
+}
+
+func TestRequestRecordAccess(t *testing.T) {
+	e := newSealedRecordEnv(t)
+
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("RequestRecordAccess", "C1", "")
+	e.mustFail("RequestRecordAccess", "C404", "discovery")
+	requestID := e.mustInvoke("RequestRecordAccess", "C1", "discovery")
+	if msg := e.mustFail("RequestRecordAccess", "C1", "discovery"); !strings.Contains(msg, "already pending") {
+		t.Fatal(msg)
+	}
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("RequestRecordAccess", "C1", "already granted")
+
+	requests := getPendingAccessRequests(e, "C1")
+	if len(requests) != 1 || requests[0].RequestID != requestID || requests[0].Requester != "bob" || requests[0].Status != AccessRequestPending {
+		t.Fatalf("unexpected pending requests %+v", requests)
+	}
+}
+
+func TestApproveAccessRequest(t *testing.T) {
+	e := newSealedRecordEnv(t)
+	e.as("Org1MSP", "bob", "client")
+	requestID := e.mustInvoke("RequestRecordAccess", "C1", "discovery")
+	e.mustFail("ApproveAccessRequest", "C1", requestID, "self approval")
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("ApproveAccessRequest", "C1", "unknown", "ok")
+	e.mustInvoke("ApproveAccessRequest", "C1", requestID, "ok")
+	if msg := e.mustFail("ApproveAccessRequest", "C1", requestID, "again"); !strings.Contains(msg, "already APPROVED") {
+		t.Fatal(msg)
+	}
+	if requests := getPendingAccessRequests(e, "C1"); len(requests) != 0 {
+		t.Fatalf("approved request is still pending: %+v", requests)
+	}
+
+	var legalRecord LegalRecord
+	err := json.Unmarshal(e.stub.State["C1"], &legalRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsString(legalRecord.UsersWithAccess, "bob") {
+		t.Fatalf("bob was not granted access: %v", legalRecord.UsersWithAccess)
+	}
+
+	e.as("Org1MSP", "bob", "client")
+	e.mustInvoke("ReadLegalRecordAudited", "C1", "discovery")
+}
+
+func TestDenyAccessRequest(t *testing.T) {
+	e := newSealedRecordEnv(t)
+	e.as("Org1MSP", "bob", "client")
+	requestID := e.mustInvoke("RequestRecordAccess", "C1", "discovery")
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("DenyAccessRequest", "C1", requestID, "")
+	e.mustInvoke("DenyAccessRequest", "C1", requestID, "not a party")
+	e.mustFail("ApproveAccessRequest", "C1", requestID, "ok")
+	if requests := getPendingAccessRequests(e, "C1"); len(requests) != 0 {
+		t.Fatalf("denied request is still pending: %+v", requests)
+	}
+
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("ReadLegalRecordAudited", "C1", "discovery")
+	e.mustInvoke("RequestRecordAccess", "C1", "new evidence")
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	contentHashObjectType = "contentHash"
+
+	ContentLegalRecord = "legalRecord"
+	ContentUser        = "user"
+	ContentDocument    = "document"
+	ContentFiling      = "filing"
+)
+
+// canonicalTimestampFields are the fields of stored records holding RFC 3339 timestamps, which are
+// written in UTC in canonical form
+var canonicalTimestampFields = map[string]bool{
+	"dateCreated": true,
+	"lastUpdated": true,
+	"createdAt":   true,
+	"submittedAt": true,
+	"reviewedAt":  true,
+	"attachedAt":  true,
+}
+
+// ContentHash is the hex encoded SHA-256 of the canonical JSON of a stored record. Legal records, users,
+// documents and filings are stored in canonical form, so the hash can be checked byte for byte against the
+// world state value or recomputed off-chain from the record.
+type ContentHash struct {
+	RecordType string `json:"recordType"`
+	RecordID   string `json:"recordID"`
+	CaseID     string `json:"caseID,omitempty" metadata:"caseID,optional"`
+	Hash       string `json:"hash"`
+	TxID       string `json:"txID"`
+}
+
+// QueryLegalRecordHash returns the content hash of a legal record
+func (s *SmartContract) QueryLegalRecordHash(ctx contractapi.TransactionContextInterface, caseID string) (*ContentHash, error) {
+	return getContentHash(ctx, ContentLegalRecord, caseID)
+}
+
+// QueryUserHash returns the content hash of a user
+func (s *SmartContract) QueryUserHash(ctx contractapi.TransactionContextInterface, userID string) (*ContentHash, error) {
+	return getContentHash(ctx, ContentUser, userID)
+}
+
+// QueryDocumentHash returns the content hash of a document attached to a case
+func (s *SmartContract) QueryDocumentHash(ctx contractapi.TransactionContextInterface, caseID string, documentRef string) (*ContentHash, error) {
+	return getContentHash(ctx, ContentDocument, caseID, documentRef)
+}
+
+// QueryFilingHash returns the content hash of a filing in a case
+func (s *SmartContract) QueryFilingHash(ctx contractapi.TransactionContextInterface, caseID string, filingID string) (*ContentHash, error) {
+	return getContentHash(ctx, ContentFiling, caseID, filingID)
+}
+
+// canonicalJSON serializes a value as canonical JSON: object keys sorted by code point, no insignificant
+// whitespace or HTML escaping, strings in Unicode NFC and the RFC 3339 timestamps of the fields in
+// canonicalTimestampFields in UTC. Numbers are kept as encoding/json writes them.
+func canonicalJSON(value interface{}) ([]byte, error) {
+	valueAsBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(valueAsBytes))
+	decoder.UseNumber()
+	var generic interface{}
+	err = decoder.Decode(&generic)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	err = encoder.Encode(canonicalValue(generic))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// canonicalValue normalizes the strings of a decoded JSON value. Maps are written with sorted keys
+// by encoding/json, so keys only need normalizing. Other strings that happen to parse as timestamps,
+// such as descriptions, are left as given apart from NFC.
+func canonicalValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if text, ok := item.(string); ok && canonicalTimestampFields[key] {
+				if timestamp, err := time.Parse(time.RFC3339Nano, text); err == nil {
+					normalized[key] = timestamp.UTC().Format(time.RFC3339Nano)
+					continue
+				}
+			}
+			normalized[norm.NFC.String(key)] = canonicalValue(item)
+		}
+		return normalized
+	case []interface{}:
+		for i, item := range v {
+			v[i] = canonicalValue(item)
+		}
+		return v
+	case string:
+		return norm.NFC.String(v)
+	default:
+		return v
+	}
+}
+
+// putCanonicalState stores a record under key in canonical form together with its content hash,
+// identified by the record type and ids. It returns the stored bytes.
+func putCanonicalState(ctx contractapi.TransactionContextInterface, key string, value interface{}, recordType string, ids ...string) ([]byte, error) {
+	valueAsBytes, err := canonicalJSON(value)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal %s. %s", recordType, err.Error())
+	}
+	err = ctx.GetStub().PutState(key, valueAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put %s. %s", recordType, err.Error())
+	}
+
+	contentHash := &ContentHash{
+		RecordType: recordType,
+		RecordID:   ids[len(ids)-1],
+		Hash:       hashRecord(valueAsBytes),
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	if len(ids) > 1 {
+		contentHash.CaseID = ids[0]
+	}
+	hashKey, err := ctx.GetStub().CreateCompositeKey(contentHashObjectType, append([]string{recordType}, ids...))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	contentHashAsBytes, err := json.Marshal(contentHash)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal content hash. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(hashKey, contentHashAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put content hash. %s", err.Error())
+	}
+
+	return valueAsBytes, nil
+}
+
+func getContentHash(ctx contractapi.TransactionContextInterface, recordType string, ids ...string) (*ContentHash, error) {
+	hashKey, err := ctx.GetStub().CreateCompositeKey(contentHashObjectType, append([]string{recordType}, ids...))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	contentHashAsBytes, err := ctx.GetStub().GetState(hashKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if contentHashAsBytes == nil {
+		return nil, fmt.Errorf("No content hash stored for %s %s", recordType, ids[len(ids)-1])
+	}
+
+	contentHash := new(ContentHash)
+	err = json.Unmarshal(contentHashAsBytes, contentHash)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal content hash. %s", err.Error())
+	}
+	return contentHash, nil
+}
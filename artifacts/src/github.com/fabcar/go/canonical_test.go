@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// requireStoredCanonically checks the world state value under key is in canonical form and matches
+// the content hash returned by the query
+func requireStoredCanonically(e *testEnv, key string, contentHashJSON string) {
+	e.t.Helper()
+	stored := e.stub.State[key]
+	var generic interface{}
+	err := json.Unmarshal(stored, &generic)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	canonical, err := canonicalJSON(generic)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	if !bytes.Equal(stored, canonical) {
+		e.t.Fatalf("%s is not stored canonically: %s", key, stored)
+	}
+	if hash := jsonField(e.t, contentHashJSON, "hash"); hash != hashRecord(stored) {
+		e.t.Fatalf("content hash %s does not match %s", hash, stored)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	canonical, err := canonicalJSON(map[string]interface{}{
+		"name":        "Jose\u0301 <b>",
+		"createdAt":   "2024-03-01T12:00:00+02:00",
+		"description": "2024-03-01T12:00:00+02:00",
+		"cafe\u0301":  []interface{}{"Mu\u0308ller", 1.5, map[string]interface{}{"reviewedAt": "2024-03-01T00:00:00.5-05:00", "b": true, "a": nil}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\"caf\u00e9\":[\"M\u00fcller\",1.5,{\"a\":null,\"b\":true,\"reviewedAt\":\"2024-03-01T05:00:00.5Z\"}]," +
+		"\"createdAt\":\"2024-03-01T10:00:00Z\",\"description\":\"2024-03-01T12:00:00+02:00\",\"name\":\"Jos\u00e9 <b>\"}"
+	if string(canonical) != expected {
+		t.Fatalf("unexpected canonical JSON %s", canonical)
+	}
+
+	// Composed and decomposed forms of the same user hash alike
+	composed, err := canonicalJSON(&User{ID: "u1", Name: "Jos\u00e9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decomposed, err := canonicalJSON(&User{ID: "u1", Name: "Jose\u0301"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashRecord(composed) != hashRecord(decomposed) {
+		t.Fatalf("%s and %s hash differently", composed, decomposed)
+	}
+}
+
+func TestContentHashes(t *testing.T) {
+	e := newFilingEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("QueryLegalRecordHash", "C9")
+
+	legalRecordHash := e.mustInvoke("QueryLegalRecordHash", "C1")
+	requireStoredCanonically(e, "C1", legalRecordHash)
+	e.mustInvoke("AddParticipant", "C1", `{"id":"w1","name":"Jose\u0301","role":"witness"}`)
+	updatedHash := e.mustInvoke("QueryLegalRecordHash", "C1")
+	requireStoredCanonically(e, "C1", updatedHash)
+	if jsonField(t, updatedHash, "hash") == jsonField(t, legalRecordHash, "hash") || jsonField(t, updatedHash, "txID") == jsonField(t, legalRecordHash, "txID") {
+		t.Fatalf("content hash %s was not updated from %s", updatedHash, legalRecordHash)
+	}
+	if participants := getStoredLegalRecord(e, "C1").Participants; participants[2].Name != "Jos\u00e9" {
+		t.Fatalf("unexpected participants %+v", participants)
+	}
+
+	e.mustInvoke("CreateUser", `{"id":"u1","name":"Mu\u0308ller","type":"client"}`)
+	requireStoredCanonically(e, "u1", e.mustInvoke("QueryUserHash", "u1"))
+
+	e.as("Org1MSP", "bob", "lawyer")
+	filingID := jsonField(t, e.mustInvoke("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1"}`), "recordID")
+	e.mustInvoke("SubmitFiling", "C1", filingID)
+	e.as("Org1MSP", "clerk1", "clerk")
+	e.mustInvoke("AcceptFiling", "C1", filingID, "")
+
+	filingKey, err := e.stub.CreateCompositeKey(filingObjectType, []string{"C1", filingID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	documentKey, err := e.stub.CreateCompositeKey(documentObjectType, []string{"C1", "doc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another organization can check the stored records against their hashes
+	e.as("Org2MSP", "admin2", "approver")
+	filingHash := e.mustInvoke("QueryFilingHash", "C1", filingID)
+	requireStoredCanonically(e, filingKey, filingHash)
+	if jsonField(t, filingHash, "caseID") != "C1" || jsonField(t, filingHash, "recordType") != ContentFiling {
+		t.Fatalf("unexpected content hash %s", filingHash)
+	}
+	requireStoredCanonically(e, documentKey, e.mustInvoke("QueryDocumentHash", "C1", "doc1"))
+	e.mustFail("QueryDocumentHash", "C1", "doc2")
+	e.mustFail("QueryFilingHash", "C2", filingID)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// caseNumberSequenceObjectType keys hold the last sequence number used for a zip code, case type and
+// year. Each combination has its own key, so filings of other zip codes or types never conflict on it.
+const caseNumberSequenceObjectType = "caseNumberSequence"
+
+// caseNumberReservationObjectType keys hold the court a case number from GenerateCaseNumber was
+// reserved for, until a legal record is created with it
+const caseNumberReservationObjectType = "caseNumberReservation"
+
+// caseNumberTypeCodeObjectType keys hold the case type a case number type code was first generated
+// for, so two case types that normalize to the same code cannot share numbers
+const caseNumberTypeCodeObjectType = "caseNumberTypeCode"
+
+// generatedCaseNumberPattern matches the YEAR-SEQ suffix of generated case numbers. Client supplied
+// case IDs in this form are only accepted when they were reserved with GenerateCaseNumber.
+var generatedCaseNumberPattern = regexp.MustCompile(`-[0-9]{4}-[0-9]{6}$`)
+
+// GenerateCaseNumber reserves the next case number of a court for the given case type. Numbers have
+// the form ZIP-TYPE-YEAR-SEQ, using the court's first zip code and the year of the transaction.
+// The number can then be passed as the case ID of a legal record of that court.
+func (s *SmartContract) GenerateCaseNumber(ctx contractapi.TransactionContextInterface, courtID string, caseType string) (string, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return "", err
+	}
+
+	court, err := getCourt(ctx, courtID)
+	if err != nil {
+		return "", err
+	}
+	err = requireCourtOwner(ctx, court.OwnerMSP)
+	if err != nil {
+		return "", err
+	}
+
+	caseNumber, err := nextCaseNumber(ctx, court.ZipCodes[0], caseType)
+	if err != nil {
+		return "", err
+	}
+	reservationKey, err := ctx.GetStub().CreateCompositeKey(caseNumberReservationObjectType, []string{caseNumber})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(reservationKey, []byte(court.ID))
+	if err != nil {
+		return "", fmt.Errorf("Failed to put case number reservation. %s", err.Error())
+	}
+
+	ctx.GetStub().SetEvent("GenerateCaseNumber", []byte(caseNumber))
+
+	return caseNumber, nil
+}
+
+// requireCaseIDAvailable checks a client supplied case ID for a new legal record of a court. IDs in
+// the generated form must have been reserved for the court and the reservation is used up.
+func requireCaseIDAvailable(ctx contractapi.TransactionContextInterface, caseID string, court *Court) error {
+	if !generatedCaseNumberPattern.MatchString(caseID) {
+		return nil
+	}
+
+	reservationKey, err := ctx.GetStub().CreateCompositeKey(caseNumberReservationObjectType, []string{caseID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	reservedFor, err := ctx.GetStub().GetState(reservationKey)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if string(reservedFor) != court.ID {
+		return fmt.Errorf("%s has the form of a generated case number and was not reserved for court %s", caseID, court.ID)
+	}
+	err = ctx.GetStub().DelState(reservationKey)
+	if err != nil {
+		return fmt.Errorf("Failed to delete case number reservation. %s", err.Error())
+	}
+	return nil
+}
+
+// nextCaseNumber increments the sequence of the zip code and case type for the transaction year and
+// formats a case number. Courts sharing a zip code share the sequence, and numbers already taken by
+// an existing record or reservation are skipped.
+func nextCaseNumber(ctx contractapi.TransactionContextInterface, zipCode string, caseType string) (string, error) {
+	typeCode, err := getCaseTypeCode(ctx, caseType)
+	if err != nil {
+		return "", err
+	}
+
+	createdAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	year := createdAt[:4]
+
+	sequenceKey, err := ctx.GetStub().CreateCompositeKey(caseNumberSequenceObjectType, []string{zipCode, typeCode, year})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	sequenceAsBytes, err := ctx.GetStub().GetState(sequenceKey)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	sequence := 0
+	if sequenceAsBytes != nil {
+		sequence, err = strconv.Atoi(string(sequenceAsBytes))
+		if err != nil {
+			return "", fmt.Errorf("Invalid case number sequence for %s %s. %s", zipCode, typeCode, err.Error())
+		}
+	}
+
+	var caseNumber string
+	for {
+		sequence++
+		caseNumber = fmt.Sprintf("%s-%s-%s-%06d", zipCode, typeCode, year, sequence)
+		taken, err := isCaseNumberTaken(ctx, caseNumber)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			break
+		}
+	}
+
+	err = ctx.GetStub().PutState(sequenceKey, []byte(strconv.Itoa(sequence)))
+	if err != nil {
+		return "", fmt.Errorf("Failed to put case number sequence. %s", err.Error())
+	}
+
+	return caseNumber, nil
+}
+
+// isCaseNumberTaken reports whether a legal record or a reservation already uses the case number
+func isCaseNumberTaken(ctx contractapi.TransactionContextInterface, caseNumber string) (bool, error) {
+	reservationKey, err := ctx.GetStub().CreateCompositeKey(caseNumberReservationObjectType, []string{caseNumber})
+	if err != nil {
+		return false, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	for _, key := range []string{caseNumber, reservationKey} {
+		existing, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return false, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+		if existing != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getCaseTypeCode returns the case number code of a case type. The first case type generating a code
+// claims it; other case types with the same code, such as "small-claims" and "smallclaims", are
+// rejected instead of sharing its numbers.
+func getCaseTypeCode(ctx contractapi.TransactionContextInterface, caseType string) (string, error) {
+	typeCode := caseNumberCode(caseType)
+	if len(typeCode) == 0 {
+		return "", fmt.Errorf("A case type is required to generate a case number")
+	}
+	normalized := strings.Join(strings.Fields(strings.ToLower(caseType)), " ")
+
+	typeCodeKey, err := ctx.GetStub().CreateCompositeKey(caseNumberTypeCodeObjectType, []string{typeCode})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	claimedBy, err := ctx.GetStub().GetState(typeCodeKey)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if claimedBy == nil {
+		err = ctx.GetStub().PutState(typeCodeKey, []byte(normalized))
+		if err != nil {
+			return "", fmt.Errorf("Failed to put case type code. %s", err.Error())
+		}
+	} else if string(claimedBy) != normalized {
+		return "", fmt.Errorf("Case type %s has the case number code %s of case type %s", caseType, typeCode, string(claimedBy))
+	}
+	return typeCode, nil
+}
+
+// caseNumberCode keeps the letters and digits of a value, upper cased, for use in a case number
+func caseNumberCode(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, value)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// caseNumber formats the expected case number of the current year, the year of mock transactions
+func caseNumber(prefix string, sequence string) string {
+	return prefix + "-" + time.Now().UTC().Format("2006") + "-" + sequence
+}
+
+func TestGenerateCaseNumber(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+
+	if number := e.mustInvoke("GenerateCaseNumber", "CT1", "civil"); number != caseNumber("10001-CIVIL", "000001") {
+		t.Fatal(number)
+	}
+	if number := e.mustInvoke("GenerateCaseNumber", "CT1", "civil"); number != caseNumber("10001-CIVIL", "000002") {
+		t.Fatal(number)
+	}
+	// Each case type has its own sequence
+	if number := e.mustInvoke("GenerateCaseNumber", "CT1", "Family Law"); number != caseNumber("10001-FAMILYLAW", "000001") {
+		t.Fatal(number)
+	}
+	e.mustFail("GenerateCaseNumber", "CT1", " - ")
+	e.mustFail("GenerateCaseNumber", "CT404", "civil")
+	e.mustFail("GenerateCaseNumber", "CT2", "civil")
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("GenerateCaseNumber", "CT1", "civil")
+
+	// Each zip code has its own sequence
+	e.as("Org2MSP", "admin2", "approver")
+	if number := e.mustInvoke("GenerateCaseNumber", "CT2", "civil"); number != caseNumber("20001-CIVIL", "000001") {
+		t.Fatal(number)
+	}
+}
+
+func TestGenerateCaseNumberAvoidsCollisions(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateCourt", `{"id":"CT3","name":"District 3","type":"district","category":"civil","zipCodes":["10001"]}`)
+
+	// Courts sharing a zip code share its sequence
+	first := e.mustInvoke("GenerateCaseNumber", "CT1", "civil")
+	second := e.mustInvoke("GenerateCaseNumber", "CT3", "civil")
+	if first != caseNumber("10001-CIVIL", "000001") || second != caseNumber("10001-CIVIL", "000002") {
+		t.Fatalf("numbers %s and %s", first, second)
+	}
+
+	// Numbers already used as a case ID or reserved are skipped
+	taken := caseNumber("10001-CIVIL", "000003")
+	reserved := caseNumber("10001-CIVIL", "000004")
+	reservationKey, err := e.stub.CreateCompositeKey(caseNumberReservationObjectType, []string{reserved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.stub.MockTransactionStart("taken")
+	e.stub.PutState(taken, []byte(`{"caseID":"`+taken+`"}`))
+	e.stub.PutState(reservationKey, []byte("CT1"))
+	e.stub.MockTransactionEnd("taken")
+	if number := e.mustInvoke("GenerateCaseNumber", "CT3", "civil"); number != caseNumber("10001-CIVIL", "000005") {
+		t.Fatal(number)
+	}
+	if string(e.stub.State[reservationKey]) != "CT1" {
+		t.Fatalf("reservation of %s was overwritten", reserved)
+	}
+}
+
+func TestCaseTypeCodeCollisions(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+
+	e.mustInvoke("GenerateCaseNumber", "CT1", "small-claims")
+	e.mustInvoke("GenerateCaseNumber", "CT1", " Small-Claims ")
+	if msg := e.mustFail("GenerateCaseNumber", "CT1", "smallclaims"); !strings.Contains(msg, "small-claims") {
+		t.Fatal(msg)
+	}
+	e.mustFail("CreateLegalRecord", toJSON(t, map[string]interface{}{"courtID": "CT1", "caseType": "small claims", "judges": []string{}}))
+
+	// The claim is shared by all courts
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("GenerateCaseNumber", "CT2", "smallclaims")
+	if number := e.mustInvoke("GenerateCaseNumber", "CT2", "Small-claims"); number != caseNumber("20001-SMALLCLAIMS", "000001") {
+		t.Fatal(number)
+	}
+}
+
+func TestCreateLegalRecordCaseNumbers(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+
+	// Records created without a case ID are numbered with their zip code and case type
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"courtID": "CT1", "courtZip": "10002", "caseType": "probate", "judges": []string{}}))
+	generated := caseNumber("10002-PROBATE", "000001")
+	if getStoredLegalRecord(e, generated).CaseID != generated {
+		t.Fatalf("no record stored under %s", generated)
+	}
+	e.mustFail("CreateLegalRecord", toJSON(t, map[string]interface{}{"courtID": "CT1", "judges": []string{}}))
+
+	// Generated numbers can only be used for the court they were reserved for, and only once
+	reserved := e.mustInvoke("GenerateCaseNumber", "CT1", "civil")
+	e.mustFail("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": caseNumber("10001-CIVIL", "000099"), "courtID": "CT1", "judges": []string{}}))
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": reserved, "courtID": "CT2", "judges": []string{}}))
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": reserved, "courtID": "CT1", "judges": []string{}}))
+	e.mustFail("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": reserved, "courtID": "CT1", "judges": []string{}}))
+
+	// Other client supplied IDs are still accepted
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "LEGACY-42", "courtID": "CT1", "judges": []string{}}))
+}
+
+func TestCaseNumberCode(t *testing.T) {
+	for value, code := range map[string]string{
+		"civil":        "CIVIL",
+		"Family Law":   "FAMILYLAW",
+		"small-claims": "SMALLCLAIMS",
+		" - ":          "",
+	} {
+		if got := caseNumberCode(value); got != code {
+			t.Errorf("caseNumberCode(%q) = %q, want %q", value, got, code)
+		}
+	}
+}
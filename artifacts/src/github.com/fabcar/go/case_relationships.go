@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	caseRelationshipObjectType = "caseRelationship"
+
+	defaultCaseLineageDepth = 3
+	maxCaseLineageDepth     = 10
+
+	CaseStatusClosed = "CLOSED"
+
+	RelationshipAppealOf         = "APPEAL_OF"
+	RelationshipRemandedFrom     = "REMANDED_FROM"
+	RelationshipRelatedTo        = "RELATED_TO"
+	RelationshipConsolidatedInto = "CONSOLIDATED_INTO"
+)
+
+// CaseRelationship is a typed link from one case to another, e.g. an appeal (CaseID) of a lower
+// court case (RelatedCaseID). It is stored under the keys of both cases so either side can find it.
+type CaseRelationship struct {
+	CaseID        string `json:"caseID"`
+	RelatedCaseID string `json:"relatedCaseID"`
+	Type          string `json:"type"`
+	CreatedBy     string `json:"createdBy"`
+	CreatedAt     string `json:"createdAt"`
+	TxID          string `json:"txID"`
+}
+
+// CaseLineageNode is a case reached while walking the relationship graph, with the relationship
+// it was reached through
+type CaseLineageNode struct {
+	CaseID       string            `json:"caseID"`
+	Depth        int               `json:"depth"`
+	Relationship *CaseRelationship `json:"relationship,omitempty" metadata:"relationship,optional"`
+}
+
+// LinkCases records a relationship from caseID to relatedCaseID. Appeals must point to a closed case
+// of a court whose appellate court is the appeal's court, and remands to a case of the appellate court.
+func (s *SmartContract) LinkCases(ctx contractapi.TransactionContextInterface, caseID string, relationshipType string, relatedCaseID string) error {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return err
+	}
+	if caseID == relatedCaseID {
+		return fmt.Errorf("A case cannot be related to itself")
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if len(legalRecord.OwnerMSP) > 0 {
+		err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+		if err != nil {
+			return err
+		}
+	}
+	relatedRecord, err := getLegalRecord(ctx, relatedCaseID)
+	if err != nil {
+		return err
+	}
+
+	relationshipType = strings.ToUpper(relationshipType)
+	err = validateCaseRelationship(ctx, legalRecord, relationshipType, relatedRecord)
+	if err != nil {
+		return err
+	}
+
+	relationship, err := putCaseRelationship(ctx, caseID, relationshipType, relatedCaseID)
+	if err != nil {
+		return err
+	}
+
+	relationshipAsBytes, err := json.Marshal(relationship)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal case relationship. %s", err.Error())
+	}
+	return ctx.GetStub().SetEvent("LinkCases", relationshipAsBytes)
+}
+
+// GetCaseRelationships returns the relationships from and to a case whose other case the caller
+// may also read
+func (s *SmartContract) GetCaseRelationships(ctx contractapi.TransactionContextInterface, caseID string) ([]*CaseRelationship, error) {
+	if len(caseID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct case id")
+	}
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+	return getReadableCaseRelationships(ctx, caseID)
+}
+
+// GetCaseLineage walks the relationships of a case in both directions and returns every case reachable
+// within maxDepth links, nearest first. A maxDepth of 0 uses the default depth. The walk does not
+// enter cases the caller may not read.
+func (s *SmartContract) GetCaseLineage(ctx contractapi.TransactionContextInterface, caseID string, maxDepth int) ([]*CaseLineageNode, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+	if maxDepth <= 0 {
+		maxDepth = defaultCaseLineageDepth
+	}
+	if maxDepth > maxCaseLineageDepth {
+		return nil, fmt.Errorf("Lineage depth cannot exceed %d", maxCaseLineageDepth)
+	}
+
+	lineage := []*CaseLineageNode{{CaseID: caseID}}
+	visited := map[string]bool{caseID: true}
+	for next := 0; next < len(lineage); next++ {
+		node := lineage[next]
+		if node.Depth == maxDepth {
+			continue
+		}
+
+		relationships, err := getReadableCaseRelationships(ctx, node.CaseID)
+		if err != nil {
+			return nil, err
+		}
+		for _, relationship := range relationships {
+			other := relationship.RelatedCaseID
+			if other == node.CaseID {
+				other = relationship.CaseID
+			}
+			if visited[other] {
+				continue
+			}
+			visited[other] = true
+			lineage = append(lineage, &CaseLineageNode{CaseID: other, Depth: node.Depth + 1, Relationship: relationship})
+		}
+	}
+
+	return lineage, nil
+}
+
+// validateCaseRelationship checks the type of a new relationship and the rules that come with it
+func validateCaseRelationship(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, relationshipType string, relatedRecord *LegalRecord) error {
+	existing, err := getCaseRelationships(ctx, legalRecord.CaseID)
+	if err != nil {
+		return err
+	}
+	for _, relationship := range existing {
+		if relationship.Type == relationshipType && (relationship.RelatedCaseID == relatedRecord.CaseID || relationship.CaseID == relatedRecord.CaseID) {
+			return fmt.Errorf("%s is already %s %s", legalRecord.CaseID, strings.ToLower(relationshipType), relatedRecord.CaseID)
+		}
+	}
+
+	switch relationshipType {
+	case RelationshipAppealOf:
+		if !strings.EqualFold(relatedRecord.Status, CaseStatusClosed) {
+			return fmt.Errorf("Only closed cases can be appealed; %s is %s", relatedRecord.CaseID, relatedRecord.Status)
+		}
+		return requireAppellateCourt(ctx, relatedRecord, legalRecord)
+	case RelationshipRemandedFrom:
+		return requireAppellateCourt(ctx, legalRecord, relatedRecord)
+	case RelationshipConsolidatedInto:
+		return fmt.Errorf("Use ConsolidateCases to consolidate cases")
+	case RelationshipRelatedTo:
+	default:
+		return fmt.Errorf("Invalid case relationship type: %s", relationshipType)
+	}
+	return nil
+}
+
+// requireAppellateCourt checks that upperRecord belongs to the court hearing appeals from the court of lowerRecord
+func requireAppellateCourt(ctx contractapi.TransactionContextInterface, lowerRecord *LegalRecord, upperRecord *LegalRecord) error {
+	if len(lowerRecord.CourtID) == 0 || len(upperRecord.CourtID) == 0 {
+		return fmt.Errorf("Both cases must reference a registered court")
+	}
+	lowerCourt, err := getCourt(ctx, lowerRecord.CourtID)
+	if err != nil {
+		return err
+	}
+	if lowerCourt.AppellateCourtID != upperRecord.CourtID {
+		return fmt.Errorf("Court %s does not hear appeals from court %s", upperRecord.CourtID, lowerCourt.ID)
+	}
+	return nil
+}
+
+// putCaseRelationship writes a relationship under the keys of both cases
+func putCaseRelationship(ctx contractapi.TransactionContextInterface, caseID string, relationshipType string, relatedCaseID string) (*CaseRelationship, error) {
+	createdBy, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	relationship := &CaseRelationship{
+		CaseID:        caseID,
+		RelatedCaseID: relatedCaseID,
+		Type:          relationshipType,
+		CreatedBy:     createdBy,
+		CreatedAt:     createdAt,
+		TxID:          ctx.GetStub().GetTxID(),
+	}
+	relationshipAsBytes, err := json.Marshal(relationship)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal case relationship. %s", err.Error())
+	}
+
+	for _, attributes := range [][]string{{caseID, relatedCaseID, relationshipType}, {relatedCaseID, caseID, relationshipType}} {
+		relationshipKey, err := ctx.GetStub().CreateCompositeKey(caseRelationshipObjectType, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+		}
+		err = ctx.GetStub().PutState(relationshipKey, relationshipAsBytes)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to put case relationship. %s", err.Error())
+		}
+	}
+	return relationship, nil
+}
+
+// getReadableCaseRelationships returns the relationships of a case without those leading to a case
+// the caller may not read, so links between sealed cases are not revealed
+func getReadableCaseRelationships(ctx contractapi.TransactionContextInterface, caseID string) ([]*CaseRelationship, error) {
+	relationships, err := getCaseRelationships(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := []*CaseRelationship{}
+	for _, relationship := range relationships {
+		other := relationship.RelatedCaseID
+		if other == caseID {
+			other = relationship.CaseID
+		}
+		otherRecord, err := getLegalRecord(ctx, other)
+		if err != nil {
+			return nil, err
+		}
+		_, err = requireCaseReader(ctx, otherRecord)
+		if err != nil {
+			continue
+		}
+		readable = append(readable, relationship)
+	}
+	return readable, nil
+}
+
+func getCaseRelationships(ctx contractapi.TransactionContextInterface, caseID string) ([]*CaseRelationship, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(caseRelationshipObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	relationships := []*CaseRelationship{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var relationship CaseRelationship
+		err = json.Unmarshal(queryResponse.Value, &relationship)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal case relationship. %s", err.Error())
+		}
+		relationships = append(relationships, &relationship)
+	}
+
+	return relationships, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newRelationshipEnv returns an environment where appellate court APP of Org2MSP hears appeals from
+// CT1, with the closed case L1 and open case L2 of CT1, the sealed case S1 of CT1 and the appeal
+// case A1 of APP
+func newRelationshipEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("CreateCourt", `{"id":"APP","name":"Appeals","type":"appellate","category":"civil","zipCodes":["20002"]}`)
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"A1","courtID":"APP","judges":[],"confidentiality":"PUBLIC"}`)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("UpdateCourt", "CT1", `{"appellateCourtID":"APP"}`)
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"L1","courtID":"CT1","judges":[],"confidentiality":"PUBLIC","status":"CLOSED"}`)
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"L2","courtID":"CT1","judges":[],"confidentiality":"PUBLIC","status":"OPEN"}`)
+	e.setPrivateDetails(map[string]string{"description": "Sealed matter"})
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"S1","courtID":"CT1","judges":[],"confidentiality":"SEALED","usersWithAccess":["admin1"]}`)
+	e.setPrivateDetails(map[string]string{})
+	return e
+}
+
+func getCaseRelationshipsAs(e *testEnv, caseID string) []*CaseRelationship {
+	e.t.Helper()
+	var relationships []*CaseRelationship
+	err := json.Unmarshal([]byte(e.mustInvoke("GetCaseRelationships", caseID)), &relationships)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return relationships
+}
+
+func getCaseLineage(e *testEnv, caseID string, maxDepth string) map[string]int {
+	e.t.Helper()
+	var lineage []*CaseLineageNode
+	err := json.Unmarshal([]byte(e.mustInvoke("GetCaseLineage", caseID, maxDepth)), &lineage)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	depths := make(map[string]int)
+	for _, node := range lineage {
+		depths[node.CaseID] = node.Depth
+	}
+	return depths
+}
+
+func TestLinkAppeal(t *testing.T) {
+	e := newRelationshipEnv(t)
+
+	// Only the organization owning the appeal links it
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("LinkCases", "A1", "appeal_of", "L1")
+	e.as("Org2MSP", "clerk2", "clerk")
+	e.mustFail("LinkCases", "A1", "appeal_of", "L1")
+
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("LinkCases", "A1", "appeal_of", "A1")
+	e.mustFail("LinkCases", "A1", "appeal_of", "C9")
+	e.mustFail("LinkCases", "A1", "appeal_of", "L2")
+	e.mustFail("LinkCases", "A1", "overrules", "L1")
+	e.mustFail("LinkCases", "A1", "consolidated_into", "L1")
+	e.mustInvoke("LinkCases", "A1", "appeal_of", "L1")
+	e.mustFail("LinkCases", "A1", "appeal_of", "L1")
+
+	// Appeals from CT2 are not heard by APP
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"B1","courtID":"CT2","judges":[],"confidentiality":"PUBLIC","status":"CLOSED"}`)
+	e.mustFail("LinkCases", "A1", "appeal_of", "B1")
+
+	// A remand goes back from the appellate case to the lower court
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"R1","courtID":"CT1","judges":[],"confidentiality":"PUBLIC"}`)
+	e.mustInvoke("LinkCases", "R1", "remanded_from", "A1")
+	e.mustFail("LinkCases", "L2", "remanded_from", "L1")
+
+	relationships := getCaseRelationshipsAs(e, "L1")
+	if len(relationships) != 1 || relationships[0].CaseID != "A1" || relationships[0].RelatedCaseID != "L1" || relationships[0].Type != RelationshipAppealOf || relationships[0].CreatedBy != "admin2" {
+		t.Fatalf("unexpected relationships %+v", relationships)
+	}
+	if relationships := getCaseRelationshipsAs(e, "A1"); len(relationships) != 2 {
+		t.Fatalf("unexpected relationships %+v", relationships)
+	}
+}
+
+func TestCaseRelationshipsHideUnreadableCases(t *testing.T) {
+	e := newRelationshipEnv(t)
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("LinkCases", "A1", "appeal_of", "L1")
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("LinkCases", "S1", "related_to", "L1")
+
+	if relationships := getCaseRelationshipsAs(e, "L1"); len(relationships) != 2 {
+		t.Fatalf("unexpected relationships %+v", relationships)
+	}
+	if lineage := getCaseLineage(e, "A1", "0"); len(lineage) != 3 || lineage["L1"] != 1 || lineage["S1"] != 2 {
+		t.Fatalf("unexpected lineage %v", lineage)
+	}
+	if lineage := getCaseLineage(e, "A1", "1"); len(lineage) != 2 {
+		t.Fatalf("unexpected lineage %v", lineage)
+	}
+	e.mustFail("GetCaseLineage", "A1", "11")
+
+	// Another organization does not learn about the sealed case through the public ones
+	for _, caller := range [][]string{{"Org2MSP", "admin2", "approver"}, {"Org1MSP", "bob", "client"}} {
+		e.as(caller[0], caller[1], caller[2])
+		relationships := getCaseRelationshipsAs(e, "L1")
+		if len(relationships) != 1 || relationships[0].CaseID != "A1" {
+			t.Fatalf("%s sees relationships %+v", caller[1], relationships)
+		}
+		if lineage := getCaseLineage(e, "A1", "0"); len(lineage) != 2 {
+			t.Fatalf("%s sees lineage %v", caller[1], lineage)
+		}
+		e.mustFail("GetCaseRelationships", "S1")
+		e.mustFail("GetCaseLineage", "S1", "0")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	RelationshipParty   = "PARTY"
+	RelationshipCounsel = "COUNSEL"
+)
+
+// JudgeRelationship declares a link between a judge and a party or counsel that may create a
+// conflict of interest. Name matches a party's name, or a counsel's name or bar number.
+// Disqualifying relationships block assignment; others are flagged.
+type JudgeRelationship struct {
+	Name          string `json:"name"`
+	Kind          string `json:"kind"`
+	Description   string `json:"description"`
+	Disqualifying bool   `json:"disqualifying"`
+}
+
+// RecuseJudge removes a judge from a case because of a conflict. It can be called by the judge
+// (through the identity bound to the judge) or by an approver of the owning court.
+func (s *SmartContract) RecuseJudge(ctx contractapi.TransactionContextInterface, caseID string, judgeID string, reason string) error {
+	if len(reason) == 0 {
+		return fmt.Errorf("Please pass a reason for the recusal")
+	}
+
+	role, err := requireRole(ctx, "approver", "judge")
+	if err != nil {
+		return err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if role == "judge" {
+		_, err = getJudgeForClient(ctx, judgeID)
+	} else if len(legalRecord.OwnerMSP) > 0 {
+		err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = unassignJudge(ctx, legalRecord, judgeID, JudgeRecused, reason)
+	if err != nil {
+		return err
+	}
+
+	_, err = putLegalRecord(ctx, legalRecord)
+	return err
+}
+
+// checkJudgeConflicts matches the judge's relationships against the parties and counsel of the case.
+// It fails on a disqualifying relationship and returns the other conflicts so they can be flagged.
+func checkJudgeConflicts(legalRecord *LegalRecord, judge *Judge) ([]string, error) {
+	conflicts, disqualifying := findJudgeConflicts(legalRecord.Participants, judge)
+	if len(disqualifying) > 0 {
+		return nil, fmt.Errorf("Judge %s has a disqualifying conflict on %s with %s", judge.ID, legalRecord.CaseID, disqualifying[0])
+	}
+	return conflicts, nil
+}
+
+// findJudgeConflicts matches the judge's relationships against the parties and counsel among the
+// participants and returns the conflicts and the disqualifying ones separately
+func findJudgeConflicts(participants []Participant, judge *Judge) ([]string, []string) {
+	var conflicts, disqualifying []string
+	for _, relationship := range judge.Relationships {
+		kind := strings.ToUpper(relationship.Kind)
+		for _, participant := range participants {
+			switch {
+			case kind == RelationshipParty && isParty(participant.Role):
+				if !strings.EqualFold(participant.Name, relationship.Name) {
+					continue
+				}
+			case kind == RelationshipCounsel && participant.Role == ParticipantCounsel:
+				if !strings.EqualFold(participant.Name, relationship.Name) && !strings.EqualFold(participant.BarNumber, relationship.Name) {
+					continue
+				}
+			default:
+				continue
+			}
+			conflict := fmt.Sprintf("%s %s: %s", strings.ToLower(relationship.Kind), participant.Name, relationship.Description)
+			if relationship.Disqualifying {
+				disqualifying = append(disqualifying, conflict)
+			} else {
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+	return conflicts, disqualifying
+}
+
+// flagAssignedJudgeConflicts re-runs the conflict check for every judge already on the case after
+// participants are added. New participants do not unassign a judge or block the change: conflicts
+// that did not exist with the previous participants are written to the judge assignment history as
+// CONFLICT_FLAGGED entries, for the judge to recuse or the court to reassign the case.
+func flagAssignedJudgeConflicts(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, previous []Participant, reason string) error {
+	for _, judgeID := range legalRecord.Judges {
+		judge, err := getJudge(ctx, judgeID)
+		if err != nil {
+			// Judges assigned before the registry existed have no relationships to check
+			continue
+		}
+
+		knownConflicts, knownDisqualifying := findJudgeConflicts(previous, judge)
+		conflicts, disqualifying := findJudgeConflicts(legalRecord.Participants, judge)
+		var flagged []string
+		for _, conflict := range disqualifying {
+			if !containsString(knownDisqualifying, conflict) {
+				flagged = append(flagged, "disqualifying "+conflict)
+			}
+		}
+		for _, conflict := range conflicts {
+			if !containsString(knownConflicts, conflict) {
+				flagged = append(flagged, conflict)
+			}
+		}
+		if len(flagged) == 0 {
+			continue
+		}
+
+		logger.Warningf("Judge %s on %s has flagged conflicts: %s", judgeID, legalRecord.CaseID, strings.Join(flagged, "; "))
+		err = putJudgeAssignment(ctx, legalRecord.CaseID, judgeID, JudgeConflictFlagged, reason, flagged)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getJudgeForClient returns the registered judge bound to the calling identity
+func getJudgeForClient(ctx contractapi.TransactionContextInterface, judgeID string) (*Judge, error) {
+	judge, err := getJudge(ctx, judgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollmentID, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mspID, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get client MSP ID. %s", err.Error())
+	}
+	if judge.EnrollmentID != enrollmentID || judge.MSPID != mspID {
+		return nil, fmt.Errorf("Client identity is not bound to judge %s", judgeID)
+	}
+	return judge, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newConflictEnv returns an environment with judge k of CT1, a former shareholder of Acme and former
+// clerk of lawyer Bob Law, assigned to case C1
+func newConflictEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateJudge", toJSON(t, map[string]interface{}{
+		"id":           "k",
+		"name":         "Kim",
+		"courtID":      "CT1",
+		"active":       true,
+		"enrollmentID": "k",
+		"relationships": []map[string]interface{}{
+			{"name": "Acme", "kind": "party", "description": "shareholder", "disqualifying": true},
+			{"name": "NY1", "kind": "counsel", "description": "former clerk"},
+		},
+	}))
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{"k"}}))
+	return e
+}
+
+func getJudgeAssignmentHistory(e *testEnv, caseID string) []*JudgeAssignment {
+	e.t.Helper()
+	var history []*JudgeAssignment
+	err := json.Unmarshal([]byte(e.mustInvoke("GetJudgeAssignmentHistory", caseID)), &history)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return history
+}
+
+func TestJudgeConflictsOnAssignment(t *testing.T) {
+	e := newConflictEnv(t)
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":       "C2",
+		"courtID":      "CT1",
+		"judges":       []string{},
+		"participants": []map[string]interface{}{{"id": "p1", "name": "ACME", "role": "plaintiff"}},
+	}))
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":  "C3",
+		"courtID": "CT1",
+		"judges":  []string{},
+		"participants": []map[string]interface{}{
+			{"id": "p1", "name": "Widget Co", "role": "defendant"},
+			{"id": "l1", "name": "Bob Law", "role": "counsel", "barNumber": "NY1", "barJurisdiction": "NY", "represents": []string{"p1"}},
+		},
+	}))
+
+	// Disqualifying relationships block the assignment, others are recorded with it
+	if msg := e.mustFail("AssignJudge", "C2", "k"); !strings.Contains(msg, "disqualifying conflict") {
+		t.Fatal(msg)
+	}
+	e.mustFail("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":       "C4",
+		"courtID":      "CT1",
+		"judges":       []string{"k"},
+		"participants": []map[string]interface{}{{"id": "p1", "name": "Acme", "role": "defendant"}},
+	}))
+	e.mustInvoke("AssignJudge", "C3", "k")
+	history := getJudgeAssignmentHistory(e, "C3")
+	if len(history) != 1 || len(history[0].Conflicts) != 1 || !strings.Contains(history[0].Conflicts[0], "former clerk") {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
+
+func TestNewParticipantFlagsAssignedJudge(t *testing.T) {
+	e := newConflictEnv(t)
+
+	// A participant conflicting with the assigned judge is added and the conflict flagged for recusal
+	e.mustInvoke("AddParticipant", "C1", `{"id":"p1","name":"Acme","role":"plaintiff"}`)
+	e.mustInvoke("AddParticipant", "C1", `{"id":"w1","name":"Wendy","role":"witness"}`)
+	legalRecord := getStoredLegalRecord(e, "C1")
+	if len(legalRecord.Participants) != 2 || !containsString(legalRecord.Judges, "k") {
+		t.Fatalf("unexpected record %+v", legalRecord)
+	}
+	history := getJudgeAssignmentHistory(e, "C1")
+	if len(history) != 2 || history[1].Action != JudgeConflictFlagged || history[1].JudgeID != "k" || history[1].Reason != "participant p1 added" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if len(history[1].Conflicts) != 1 || !strings.HasPrefix(history[1].Conflicts[0], "disqualifying party Acme") {
+		t.Fatalf("unexpected conflicts %v", history[1].Conflicts)
+	}
+
+	// Consolidating a case with a conflicting participant into the judge's case is flagged as well
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":       "C2",
+		"courtID":      "CT1",
+		"judges":       []string{},
+		"participants": []map[string]interface{}{{"id": "p2", "name": "Bob Law", "role": "counsel", "barNumber": "NY1", "barJurisdiction": "NY", "represents": []string{"p3"}}, {"id": "p3", "name": "Widget Co", "role": "defendant"}},
+	}))
+	e.mustInvoke("ConsolidateCases", "C1", `["C2"]`)
+	history = getJudgeAssignmentHistory(e, "C1")
+	if len(history) != 3 || history[2].Action != JudgeConflictFlagged || history[2].Reason != "consolidation of C2" || len(history[2].Conflicts) != 1 {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
+
+func TestRecuseJudge(t *testing.T) {
+	e := newConflictEnv(t)
+
+	e.mustFail("RecuseJudge", "C1", "k", "")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("RecuseJudge", "C1", "k", "conflict")
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("RecuseJudge", "C1", "k", "conflict")
+	e.as("Org2MSP", "k", "judge")
+	e.mustFail("RecuseJudge", "C1", "k", "conflict")
+
+	e.as("Org1MSP", "k", "judge")
+	e.mustInvoke("RecuseJudge", "C1", "k", "conflict")
+	e.mustFail("RecuseJudge", "C1", "k", "conflict")
+	if legalRecord := getStoredLegalRecord(e, "C1"); len(legalRecord.Judges) != 0 {
+		t.Fatalf("judges still assigned: %v", legalRecord.Judges)
+	}
+	history := getJudgeAssignmentHistory(e, "C1")
+	if len(history) != 2 || history[1].Action != JudgeRecused || history[1].By != "k" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	caseConsolidationObjectType = "caseConsolidation"
+
+	// maxConsolidationHops bounds how many ConsolidatedInto pointers ResolveCaseID follows
+	maxConsolidationHops = 10
+
+	CaseStatusConsolidated = "CONSOLIDATED"
+)
+
+// CaseConsolidation records what was moved when a case was consolidated into a lead case, so the
+// consolidation can be undone by SeverCase. The consolidated case keeps a ConsolidatedInto pointer
+// to the lead case while it is merged. Each consolidation is kept under its transaction ID, so a
+// case consolidated into the same lead case again does not overwrite the earlier, severed one.
+type CaseConsolidation struct {
+	LeadCaseID          string        `json:"leadCaseID"`
+	CaseID              string        `json:"caseID"`
+	PreviousStatus      string        `json:"previousStatus"`
+	Participants        []Participant `json:"participants"`
+	Judges              []string      `json:"judges"`
+	MovedParticipantIDs []string      `json:"movedParticipantIDs"`
+	AddedJudges         []string      `json:"addedJudges"`
+	AddedUsers          []string      `json:"addedUsers"`
+	ConsolidatedBy      string        `json:"consolidatedBy"`
+	ConsolidatedAt      string        `json:"consolidatedAt"`
+	TxID                string        `json:"txID"`
+	SeveredBy           string        `json:"severedBy,omitempty" metadata:"severedBy,optional"`
+	SeveredAt           string        `json:"severedAt,omitempty" metadata:"severedAt,optional"`
+}
+
+// ConsolidateCases merges cases of the same court into a lead case. Their participants and judges are
+// moved to the lead case and their access grants are copied to it. The merged cases are marked
+// CONSOLIDATED and point to the lead case.
+func (s *SmartContract) ConsolidateCases(ctx contractapi.TransactionContextInterface, leadCaseID string, caseIDs []string) error {
+	leadRecord, err := getLegalRecordForJudgeChange(ctx, leadCaseID)
+	if err != nil {
+		return err
+	}
+	if len(leadRecord.ConsolidatedInto) > 0 {
+		return fmt.Errorf("%s has itself been consolidated into %s", leadCaseID, leadRecord.ConsolidatedInto)
+	}
+	if len(caseIDs) == 0 {
+		return fmt.Errorf("Please pass the cases to consolidate")
+	}
+	for i, caseID := range caseIDs {
+		if caseID == leadCaseID {
+			return fmt.Errorf("A case cannot be consolidated into itself")
+		}
+		if containsString(caseIDs[:i], caseID) {
+			return fmt.Errorf("%s is listed more than once", caseID)
+		}
+	}
+
+	consolidatedBy, err := getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	consolidatedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, caseID := range caseIDs {
+		legalRecord, err := getLegalRecordForJudgeChange(ctx, caseID)
+		if err != nil {
+			return err
+		}
+		if len(legalRecord.ConsolidatedInto) > 0 || legalRecord.Status == CaseStatusConsolidated {
+			return fmt.Errorf("%s is already consolidated into %s", caseID, legalRecord.ConsolidatedInto)
+		}
+		// A lead case carries the participants and judges of its consolidated cases, which could
+		// not be returned to them if it were merged further
+		active, err := getCaseConsolidations(ctx, caseID, true)
+		if err != nil {
+			return err
+		}
+		if len(active) > 0 {
+			return fmt.Errorf("%s is the lead case of %s; sever it first", caseID, active[0].CaseID)
+		}
+		if legalRecord.CourtID != leadRecord.CourtID {
+			return fmt.Errorf("Only cases of the same court can be consolidated")
+		}
+
+		consolidation := &CaseConsolidation{
+			LeadCaseID:          leadCaseID,
+			CaseID:              caseID,
+			PreviousStatus:      legalRecord.Status,
+			Participants:        legalRecord.Participants,
+			Judges:              legalRecord.Judges,
+			MovedParticipantIDs: []string{},
+			AddedJudges:         []string{},
+			AddedUsers:          []string{},
+			ConsolidatedBy:      consolidatedBy,
+			ConsolidatedAt:      consolidatedAt,
+			TxID:                ctx.GetStub().GetTxID(),
+		}
+		if consolidation.Participants == nil {
+			consolidation.Participants = []Participant{}
+		}
+		if consolidation.Judges == nil {
+			consolidation.Judges = []string{}
+		}
+
+		previous := append([]Participant{}, leadRecord.Participants...)
+		consolidation.MovedParticipantIDs = mergeParticipants(leadRecord, legalRecord)
+		err = validateParticipants(leadRecord)
+		if err != nil {
+			return err
+		}
+		err = flagAssignedJudgeConflicts(ctx, leadRecord, previous, "consolidation of "+caseID)
+		if err != nil {
+			return err
+		}
+
+		for _, judgeID := range consolidation.Judges {
+			err = putJudgeAssignment(ctx, caseID, judgeID, JudgeUnassigned, "consolidated into "+leadCaseID, nil)
+			if err != nil {
+				return err
+			}
+
+			added, err := moveJudgeToCase(ctx, leadRecord, judgeID, "consolidation of "+caseID)
+			if err != nil {
+				return err
+			}
+			if added {
+				consolidation.AddedJudges = append(consolidation.AddedJudges, judgeID)
+			}
+		}
+		legalRecord.Judges = []string{}
+
+		for _, user := range legalRecord.UsersWithAccess {
+			if !containsString(leadRecord.UsersWithAccess, user) {
+				leadRecord.UsersWithAccess = append(leadRecord.UsersWithAccess, user)
+				consolidation.AddedUsers = append(consolidation.AddedUsers, user)
+			}
+		}
+
+		legalRecord.Participants = nil
+		legalRecord.Status = CaseStatusConsolidated
+		legalRecord.ConsolidatedInto = leadCaseID
+		_, err = putLegalRecord(ctx, legalRecord)
+		if err != nil {
+			return err
+		}
+		_, err = putCaseRelationship(ctx, caseID, RelationshipConsolidatedInto, leadCaseID)
+		if err != nil {
+			return err
+		}
+		consolidationKey, err := ctx.GetStub().CreateCompositeKey(caseConsolidationObjectType, []string{leadCaseID, caseID, consolidation.TxID})
+		if err != nil {
+			return fmt.Errorf("Failed to create composite key. %s", err.Error())
+		}
+		err = putCaseConsolidation(ctx, consolidationKey, consolidation)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = putLegalRecord(ctx, leadRecord)
+	if err != nil {
+		return err
+	}
+
+	caseIDsAsBytes, err := json.Marshal(caseIDs)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal case ids. %s", err.Error())
+	}
+	return ctx.GetStub().SetEvent("ConsolidateCases", caseIDsAsBytes)
+}
+
+// SeverCase splits a consolidated case off its lead case again. The participants, judges and access
+// grants it brought into the lead case are returned to it and its previous status is restored.
+func (s *SmartContract) SeverCase(ctx contractapi.TransactionContextInterface, leadCaseID string, caseID string) error {
+	leadRecord, err := getLegalRecordForJudgeChange(ctx, leadCaseID)
+	if err != nil {
+		return err
+	}
+	legalRecord, err := getLegalRecordForJudgeChange(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if legalRecord.ConsolidatedInto != leadCaseID {
+		return fmt.Errorf("%s is not consolidated into %s", caseID, leadCaseID)
+	}
+	consolidation, consolidationKey, err := getCaseConsolidation(ctx, leadCaseID, caseID)
+	if err != nil {
+		return err
+	}
+
+	var participants []Participant
+	for _, participant := range leadRecord.Participants {
+		if !containsString(consolidation.MovedParticipantIDs, participant.ID) {
+			participants = append(participants, participant)
+		}
+	}
+	leadRecord.Participants = participants
+	err = validateParticipants(leadRecord)
+	if err != nil {
+		return err
+	}
+
+	var users []string
+	for _, user := range leadRecord.UsersWithAccess {
+		if !containsString(consolidation.AddedUsers, user) {
+			users = append(users, user)
+		}
+	}
+	leadRecord.UsersWithAccess = users
+
+	var judges []string
+	for _, judgeID := range leadRecord.Judges {
+		if !containsString(consolidation.AddedJudges, judgeID) {
+			judges = append(judges, judgeID)
+			continue
+		}
+		err = putJudgeAssignment(ctx, leadCaseID, judgeID, JudgeUnassigned, caseID+" severed", nil)
+		if err != nil {
+			return err
+		}
+	}
+	if judges == nil {
+		judges = []string{}
+	}
+	leadRecord.Judges = judges
+
+	legalRecord.Participants = consolidation.Participants
+	legalRecord.Status = consolidation.PreviousStatus
+	legalRecord.ConsolidatedInto = ""
+	for _, judgeID := range consolidation.Judges {
+		_, err := moveJudgeToCase(ctx, legalRecord, judgeID, "severed from "+leadCaseID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = putLegalRecord(ctx, leadRecord)
+	if err != nil {
+		return err
+	}
+	_, err = putLegalRecord(ctx, legalRecord)
+	if err != nil {
+		return err
+	}
+	err = deleteCaseRelationship(ctx, caseID, RelationshipConsolidatedInto, leadCaseID)
+	if err != nil {
+		return err
+	}
+
+	consolidation.SeveredBy, err = getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	consolidation.SeveredAt, err = getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	err = putCaseConsolidation(ctx, consolidationKey, consolidation)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("SeverCase", []byte(caseID))
+}
+
+// ResolveCaseID follows ConsolidatedInto pointers and returns the ID of the case that currently
+// carries a consolidated case
+func (s *SmartContract) ResolveCaseID(ctx contractapi.TransactionContextInterface, caseID string) (string, error) {
+	for hops := 0; hops <= maxConsolidationHops; hops++ {
+		legalRecord, err := getLegalRecord(ctx, caseID)
+		if err != nil {
+			return "", err
+		}
+		if len(legalRecord.ConsolidatedInto) == 0 {
+			return caseID, nil
+		}
+		caseID = legalRecord.ConsolidatedInto
+	}
+	return "", fmt.Errorf("Too many consolidations to resolve %s", caseID)
+}
+
+// GetCaseConsolidations returns the consolidations into a lead case, including severed ones
+func (s *SmartContract) GetCaseConsolidations(ctx contractapi.TransactionContextInterface, leadCaseID string) ([]*CaseConsolidation, error) {
+	if len(leadCaseID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct case id")
+	}
+	return getCaseConsolidations(ctx, leadCaseID, false)
+}
+
+// mergeParticipants adds the participants of a case to the lead case and returns the IDs they got
+// there. A participant already on the lead case with the same name and role is not added again;
+// other ID clashes are resolved by prefixing the ID with the case ID.
+func mergeParticipants(leadRecord *LegalRecord, legalRecord *LegalRecord) []string {
+	existing := make(map[string]Participant)
+	for _, participant := range leadRecord.Participants {
+		existing[participant.ID] = participant
+	}
+
+	ids := make(map[string]string)
+	var moved []Participant
+	for _, participant := range legalRecord.Participants {
+		if current, ok := existing[participant.ID]; ok {
+			if current.Name == participant.Name && current.Role == participant.Role {
+				ids[participant.ID] = participant.ID
+				continue
+			}
+			ids[participant.ID] = legalRecord.CaseID + "-" + participant.ID
+		} else {
+			ids[participant.ID] = participant.ID
+		}
+		moved = append(moved, participant)
+	}
+
+	movedIDs := []string{}
+	for _, participant := range moved {
+		participant.ID = ids[participant.ID]
+		var represents []string
+		for _, representedID := range participant.Represents {
+			represents = append(represents, ids[representedID])
+		}
+		participant.Represents = represents
+		leadRecord.Participants = append(leadRecord.Participants, participant)
+		movedIDs = append(movedIDs, participant.ID)
+	}
+	return movedIDs
+}
+
+// moveJudgeToCase adds a judge coming from a consolidated or severed case to the record and records
+// the assignment. Judges that are inactive, unregistered or already on the case are skipped.
+func moveJudgeToCase(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, judgeID string, reason string) (bool, error) {
+	judge, err := getJudge(ctx, judgeID)
+	if err != nil || !judge.Active || isJudgeAssigned(legalRecord, judgeID) {
+		return false, nil
+	}
+	conflicts, err := checkJudgeConflicts(legalRecord, judge)
+	if err != nil {
+		return false, err
+	}
+	legalRecord.Judges = append(legalRecord.Judges, judgeID)
+	return true, putJudgeAssignment(ctx, legalRecord.CaseID, judgeID, JudgeAssigned, reason, conflicts)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteCaseRelationship(ctx contractapi.TransactionContextInterface, caseID string, relationshipType string, relatedCaseID string) error {
+	for _, attributes := range [][]string{{caseID, relatedCaseID, relationshipType}, {relatedCaseID, caseID, relationshipType}} {
+		relationshipKey, err := ctx.GetStub().CreateCompositeKey(caseRelationshipObjectType, attributes)
+		if err != nil {
+			return fmt.Errorf("Failed to create composite key. %s", err.Error())
+		}
+		err = ctx.GetStub().DelState(relationshipKey)
+		if err != nil {
+			return fmt.Errorf("Failed to delete case relationship. %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// getCaseConsolidations returns the consolidations into a lead case, or only those not severed yet
+func getCaseConsolidations(ctx contractapi.TransactionContextInterface, leadCaseID string, activeOnly bool) ([]*CaseConsolidation, error) {
+	consolidations, _, err := queryCaseConsolidations(ctx, leadCaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*CaseConsolidation
+	for _, consolidation := range consolidations {
+		if !activeOnly || len(consolidation.SeveredAt) == 0 {
+			result = append(result, consolidation)
+		}
+	}
+	return result, nil
+}
+
+// getCaseConsolidation returns the consolidation of a case into a lead case that has not been
+// severed yet, together with its key
+func getCaseConsolidation(ctx contractapi.TransactionContextInterface, leadCaseID string, caseID string) (*CaseConsolidation, string, error) {
+	consolidations, keys, err := queryCaseConsolidations(ctx, leadCaseID, caseID)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, consolidation := range consolidations {
+		if len(consolidation.SeveredAt) == 0 {
+			return consolidation, keys[i], nil
+		}
+	}
+	return nil, "", fmt.Errorf("%s has no consolidation into %s", caseID, leadCaseID)
+}
+
+func queryCaseConsolidations(ctx contractapi.TransactionContextInterface, attributes ...string) ([]*CaseConsolidation, []string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(caseConsolidationObjectType, attributes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var consolidations []*CaseConsolidation
+	var keys []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var consolidation CaseConsolidation
+		err = json.Unmarshal(queryResponse.Value, &consolidation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to unmarshal case consolidation. %s", err.Error())
+		}
+		consolidations = append(consolidations, &consolidation)
+		keys = append(keys, queryResponse.Key)
+	}
+
+	return consolidations, keys, nil
+}
+
+func putCaseConsolidation(ctx contractapi.TransactionContextInterface, consolidationKey string, consolidation *CaseConsolidation) error {
+	consolidationAsBytes, err := json.Marshal(consolidation)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal case consolidation. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(consolidationKey, consolidationAsBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to put case consolidation. %s", err.Error())
+	}
+	return nil
+}
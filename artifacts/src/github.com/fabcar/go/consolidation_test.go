@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newConsolidationEnv returns an environment with the lead case L of CT1 assigned to judge j, case
+// M of CT1 assigned to judge k whose participant p1 clashes with the one of L, case N of CT1 and
+// case X of CT2
+func newConsolidationEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT1","active":true,"enrollmentID":"k"}`)
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":          "L",
+		"courtID":         "CT1",
+		"judges":          []string{"j"},
+		"status":          "OPEN",
+		"usersWithAccess": []string{"admin1"},
+		"participants":    []map[string]interface{}{{"id": "p1", "name": "Acme", "role": "plaintiff"}},
+	}))
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":          "M",
+		"courtID":         "CT1",
+		"judges":          []string{"k"},
+		"status":          "PENDING",
+		"usersWithAccess": []string{"admin1", "carol"},
+		"participants": []map[string]interface{}{
+			{"id": "p1", "name": "Widget Co", "role": "defendant"},
+			{"id": "w1", "name": "Wendy", "role": "witness"},
+		},
+	}))
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"N","courtID":"CT1","judges":[],"usersWithAccess":[]}`)
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"X","courtID":"CT2","judges":[],"usersWithAccess":[]}`)
+	e.as("Org1MSP", "admin1", "approver")
+	return e
+}
+
+func getCaseConsolidationsAs(e *testEnv, leadCaseID string) []*CaseConsolidation {
+	e.t.Helper()
+	var consolidations []*CaseConsolidation
+	err := json.Unmarshal([]byte(e.mustInvoke("GetCaseConsolidations", leadCaseID)), &consolidations)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return consolidations
+}
+
+func participantIDs(legalRecord *LegalRecord) []string {
+	ids := []string{}
+	for _, participant := range legalRecord.Participants {
+		ids = append(ids, participant.ID)
+	}
+	return ids
+}
+
+func TestConsolidateCases(t *testing.T) {
+	e := newConsolidationEnv(t)
+
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("ConsolidateCases", "L", `["M"]`)
+	e.mustFail("ConsolidateCases", "X", `["M"]`)
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("ConsolidateCases", "L", `["M"]`)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("ConsolidateCases", "L", `[]`)
+	e.mustFail("ConsolidateCases", "L", `["L"]`)
+	e.mustFail("ConsolidateCases", "L", `["M","M"]`)
+	e.mustFail("ConsolidateCases", "L", `["X"]`)
+	e.mustInvoke("ConsolidateCases", "L", `["M"]`)
+
+	merged := getStoredLegalRecord(e, "M")
+	if merged.Status != CaseStatusConsolidated || merged.ConsolidatedInto != "L" || len(merged.Judges) != 0 || len(merged.Participants) != 0 {
+		t.Fatalf("unexpected consolidated case %+v", merged)
+	}
+	lead := getStoredLegalRecord(e, "L")
+	if ids := participantIDs(lead); len(ids) != 3 || ids[0] != "p1" || ids[1] != "M-p1" || ids[2] != "w1" {
+		t.Fatalf("unexpected lead participants %v", ids)
+	}
+	if len(lead.Judges) != 2 || lead.Judges[1] != "k" || !containsString(lead.UsersWithAccess, "carol") {
+		t.Fatalf("unexpected lead case %+v", lead)
+	}
+	if resolved := e.mustInvoke("ResolveCaseID", "M"); resolved != "L" {
+		t.Fatalf("M resolves to %s", resolved)
+	}
+	if relationships := getCaseRelationshipsAs(e, "L"); len(relationships) != 1 || relationships[0].Type != RelationshipConsolidatedInto || relationships[0].CaseID != "M" {
+		t.Fatalf("unexpected relationships %+v", relationships)
+	}
+	e.mustFail("LinkCases", "N", "consolidated_into", "L")
+
+	// Consolidated cases and lead cases cannot be consolidated again
+	e.mustFail("ConsolidateCases", "N", `["M"]`)
+	e.mustFail("ConsolidateCases", "M", `["N"]`)
+	e.mustFail("ConsolidateCases", "N", `["L"]`)
+
+	consolidations := getCaseConsolidationsAs(e, "L")
+	if len(consolidations) != 1 || consolidations[0].PreviousStatus != "PENDING" || len(consolidations[0].AddedJudges) != 1 || consolidations[0].AddedUsers[0] != "carol" || consolidations[0].ConsolidatedBy != "admin1" {
+		t.Fatalf("unexpected consolidations %+v", consolidations)
+	}
+}
+
+func TestSeverCase(t *testing.T) {
+	e := newConsolidationEnv(t)
+	e.mustInvoke("ConsolidateCases", "L", `["M"]`)
+
+	e.mustFail("SeverCase", "L", "N")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("SeverCase", "L", "M")
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("SeverCase", "L", "M")
+	e.mustFail("SeverCase", "L", "M")
+
+	severed := getStoredLegalRecord(e, "M")
+	if severed.Status != "PENDING" || severed.ConsolidatedInto != "" || len(severed.Judges) != 1 || severed.Judges[0] != "k" {
+		t.Fatalf("unexpected severed case %+v", severed)
+	}
+	if ids := participantIDs(severed); len(ids) != 2 || ids[0] != "p1" || ids[1] != "w1" {
+		t.Fatalf("unexpected severed participants %v", ids)
+	}
+	lead := getStoredLegalRecord(e, "L")
+	if ids := participantIDs(lead); len(ids) != 1 || len(lead.Judges) != 1 || lead.Judges[0] != "j" || containsString(lead.UsersWithAccess, "carol") {
+		t.Fatalf("unexpected lead case %+v", lead)
+	}
+	if resolved := e.mustInvoke("ResolveCaseID", "M"); resolved != "M" {
+		t.Fatalf("M resolves to %s", resolved)
+	}
+
+	// Consolidating again keeps the severed consolidation
+	e.mustInvoke("ConsolidateCases", "L", `["M"]`)
+	consolidations := getCaseConsolidationsAs(e, "L")
+	severedCount := 0
+	for _, consolidation := range consolidations {
+		if len(consolidation.SeveredBy) > 0 {
+			severedCount++
+		}
+	}
+	if len(consolidations) != 2 || severedCount != 1 {
+		t.Fatalf("unexpected consolidations %+v", consolidations)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const courtObjectType = "court"
+
+// Court is a registered court. Legal records must reference one and can only be written by
+// identities of the court's owning organization. AppellateCourtID is the court that hears appeals
+// from this court, if any.
+type Court struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Type             string   `json:"type"`
+	Category         string   `json:"category"`
+	ZipCodes         []string `json:"zipCodes"`
+	OwnerMSP         string   `json:"ownerMSP"`
+	AppellateCourtID string   `json:"appellateCourtID,omitempty" metadata:"appellateCourtID,optional"`
+}
+
+// CreateCourt registers a court owned by the caller's organization
+func (s *SmartContract) CreateCourt(ctx contractapi.TransactionContextInterface, courtData string) (string, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return "", err
+	}
+	if len(courtData) == 0 {
+		return "", fmt.Errorf("Please pass the correct court data")
+	}
+
+	var court Court
+	err = json.Unmarshal([]byte(courtData), &court)
+	if err != nil {
+		return "", fmt.Errorf("Failed while unmarshalling court. %s", err.Error())
+	}
+	if len(court.ID) == 0 || len(court.Name) == 0 {
+		return "", fmt.Errorf("Court id and name are required")
+	}
+	if len(court.ZipCodes) == 0 {
+		return "", fmt.Errorf("Court %s must serve at least one zip code", court.ID)
+	}
+	if len(court.AppellateCourtID) > 0 {
+		_, err = getCourt(ctx, court.AppellateCourtID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	mspID, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return "", fmt.Errorf("Failed to get client MSP ID. %s", err.Error())
+	}
+	if len(court.OwnerMSP) == 0 {
+		court.OwnerMSP = mspID
+	}
+	if court.OwnerMSP != mspID {
+		return "", fmt.Errorf("Courts can only be registered by their owning organization %s", court.OwnerMSP)
+	}
+
+	courtKey, err := ctx.GetStub().CreateCompositeKey(courtObjectType, []string{court.ID})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	existing, err := ctx.GetStub().GetState(courtKey)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return "", fmt.Errorf("Court %s already exists", court.ID)
+	}
+
+	courtAsBytes, err := putCourt(ctx, &court)
+	if err != nil {
+		return "", err
+	}
+
+	// Only the owning organization may endorse later changes to the court
+	policy, err := endorsementPolicyForOrgs(court.OwnerMSP)
+	if err != nil {
+		return "", err
+	}
+	err = ctx.GetStub().SetStateValidationParameter(courtKey, policy)
+	if err != nil {
+		return "", fmt.Errorf("Failed to set endorsement policy for court %s. %s", court.ID, err.Error())
+	}
+	// Records can only move to an organization that can prove its endorsement
+	err = createOrgEndorsementKey(ctx, court.OwnerMSP)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.GetStub().SetEvent("CreateCourt", courtAsBytes)
+
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// UpdateCourt updates the name, type, category, zip codes or appellate court of a court owned by the caller's organization
+func (s *SmartContract) UpdateCourt(ctx contractapi.TransactionContextInterface, courtID string, updateFieldsJSON string) error {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return err
+	}
+
+	court, err := getCourt(ctx, courtID)
+	if err != nil {
+		return err
+	}
+	err = requireCourtOwner(ctx, court.OwnerMSP)
+	if err != nil {
+		return err
+	}
+
+	var updateFields map[string]interface{}
+	err = json.Unmarshal([]byte(updateFieldsJSON), &updateFields)
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal update fields: %s", err.Error())
+	}
+
+	for field, value := range updateFields {
+		switch field {
+		case "name":
+			court.Name = value.(string)
+		case "type":
+			court.Type = value.(string)
+		case "category":
+			court.Category = value.(string)
+		case "zipCodes":
+			var zipCodes []string
+			for _, zipCode := range value.([]interface{}) {
+				zipCodes = append(zipCodes, zipCode.(string))
+			}
+			if len(zipCodes) == 0 {
+				return fmt.Errorf("Court %s must serve at least one zip code", courtID)
+			}
+			court.ZipCodes = zipCodes
+		case "appellateCourtID":
+			appellateCourtID := value.(string)
+			if len(appellateCourtID) > 0 {
+				if appellateCourtID == courtID {
+					return fmt.Errorf("Court %s cannot hear its own appeals", courtID)
+				}
+				_, err = getCourt(ctx, appellateCourtID)
+				if err != nil {
+					return err
+				}
+			}
+			court.AppellateCourtID = appellateCourtID
+		default:
+			return fmt.Errorf("Invalid field name: %s", field)
+		}
+	}
+
+	courtAsBytes, err := putCourt(ctx, court)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("UpdateCourt", courtAsBytes)
+}
+
+func (s *SmartContract) QueryCourt(ctx contractapi.TransactionContextInterface, courtID string) (*Court, error) {
+	return getCourt(ctx, courtID)
+}
+
+func (s *SmartContract) QueryAllCourts(ctx contractapi.TransactionContextInterface) ([]*Court, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(courtObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var courts []*Court
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var court Court
+		err = json.Unmarshal(queryResponse.Value, &court)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal court. %s", err.Error())
+		}
+		courts = append(courts, &court)
+	}
+
+	return courts, nil
+}
+
+// servesZip reports whether the court serves the given zip code
+func (c *Court) servesZip(zipCode string) bool {
+	for _, served := range c.ZipCodes {
+		if served == zipCode {
+			return true
+		}
+	}
+	return false
+}
+
+// requireCourtOwner checks that the caller belongs to the organization owning a court or legal record
+func requireCourtOwner(ctx contractapi.TransactionContextInterface, ownerMSP string) error {
+	mspID, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("Failed to get client MSP ID. %s", err.Error())
+	}
+	if mspID != ownerMSP {
+		return fmt.Errorf("Only identities of %s may perform this action", ownerMSP)
+	}
+	return nil
+}
+
+func getCourt(ctx contractapi.TransactionContextInterface, courtID string) (*Court, error) {
+	if len(courtID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct court id")
+	}
+
+	courtKey, err := ctx.GetStub().CreateCompositeKey(courtObjectType, []string{courtID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	courtAsBytes, err := ctx.GetStub().GetState(courtKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if courtAsBytes == nil {
+		return nil, fmt.Errorf("Court %s is not registered", courtID)
+	}
+
+	court := new(Court)
+	err = json.Unmarshal(courtAsBytes, court)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal court. %s", err.Error())
+	}
+	return court, nil
+}
+
+func putCourt(ctx contractapi.TransactionContextInterface, court *Court) ([]byte, error) {
+	courtKey, err := ctx.GetStub().CreateCompositeKey(courtObjectType, []string{court.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	courtAsBytes, err := json.Marshal(court)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal court. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(courtKey, courtAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put court. %s", err.Error())
+	}
+	return courtAsBytes, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func queryCourt(e *testEnv, courtID string) *Court {
+	e.t.Helper()
+	court := new(Court)
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryCourt", courtID)), court)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return court
+}
+
+func TestCreateCourt(t *testing.T) {
+	e := newTestEnv(t)
+
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("CreateCourt", `{"id":"CT3","name":"District 3","zipCodes":["10003"]}`)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("CreateCourt", `{"id":"CT3","zipCodes":["10003"]}`)
+	e.mustFail("CreateCourt", `{"id":"CT3","name":"District 3"}`)
+	e.mustFail("CreateCourt", `{"id":"CT1","name":"District 1 again","zipCodes":["10003"]}`)
+	e.mustFail("CreateCourt", `{"id":"CT3","name":"District 3","zipCodes":["10003"],"appellateCourtID":"CT9"}`)
+	// Courts can only be registered for the caller's own organization
+	e.mustFail("CreateCourt", `{"id":"CT3","name":"District 3","zipCodes":["10003"],"ownerMSP":"Org2MSP"}`)
+
+	e.mustInvoke("CreateCourt", `{"id":"APP1","name":"Appeals 1","type":"appellate","zipCodes":["10001"]}`)
+	e.mustInvoke("CreateCourt", `{"id":"CT3","name":"District 3","zipCodes":["10003"],"appellateCourtID":"APP1"}`)
+	if court := queryCourt(e, "CT3"); court.OwnerMSP != "Org1MSP" || court.AppellateCourtID != "APP1" {
+		t.Fatalf("unexpected court %+v", court)
+	}
+	courtKey, err := e.stub.CreateCompositeKey(courtObjectType, []string{"CT3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orgs := endorsingOrgs(e, courtKey); len(orgs) != 1 || orgs[0] != "Org1MSP" {
+		t.Fatalf("unexpected court endorsers %v", orgs)
+	}
+
+	var courts []*Court
+	err = json.Unmarshal([]byte(e.mustInvoke("QueryAllCourts")), &courts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(courts) != 4 {
+		t.Fatalf("unexpected courts %+v", courts)
+	}
+}
+
+func TestUpdateCourt(t *testing.T) {
+	e := newTestEnv(t)
+
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("UpdateCourt", "CT1", `{"name":"Taken over"}`)
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("UpdateCourt", "CT1", `{"name":"Renamed"}`)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("UpdateCourt", "CT9", `{"name":"Renamed"}`)
+	e.mustFail("UpdateCourt", "CT1", `{"zipCodes":[]}`)
+	e.mustFail("UpdateCourt", "CT1", `{"appellateCourtID":"CT1"}`)
+	e.mustFail("UpdateCourt", "CT1", `{"appellateCourtID":"CT9"}`)
+	e.mustFail("UpdateCourt", "CT1", `{"ownerMSP":"Org2MSP"}`)
+
+	// Another organization's court can hear appeals
+	e.mustInvoke("UpdateCourt", "CT1", `{"name":"District One","zipCodes":["10001","10003"],"appellateCourtID":"CT2"}`)
+	court := queryCourt(e, "CT1")
+	if court.Name != "District One" || !court.servesZip("10003") || court.servesZip("10002") || court.AppellateCourtID != "CT2" || court.OwnerMSP != "Org1MSP" {
+		t.Fatalf("unexpected court %+v", court)
+	}
+}
+
+func TestLegalRecordCourt(t *testing.T) {
+	e := newTestEnv(t)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("CreateLegalRecord", `{"caseID":"C1","judges":[]}`)
+	e.mustFail("CreateLegalRecord", `{"caseID":"C1","courtID":"CT9","judges":[]}`)
+	e.mustFail("CreateLegalRecord", `{"caseID":"C1","courtID":"CT1","courtZip":"20001","judges":[]}`)
+	// Records of another organization's court cannot be created
+	e.mustFail("CreateLegalRecord", `{"caseID":"C1","courtID":"CT2","judges":[]}`)
+
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"C1","courtID":"CT1","courtType":"supreme","judges":[]}`)
+	legalRecord := getStoredLegalRecord(e, "C1")
+	if legalRecord.OwnerMSP != "Org1MSP" || legalRecord.CourtZip != "10001" || legalRecord.CourtType != "district" || legalRecord.CourtCategory != "civil" {
+		t.Fatalf("record does not take its court's details %+v", legalRecord)
+	}
+
+	e.mustFail("UpdateLegalRecord", "C1", `{"courtType":"supreme"}`)
+	e.mustFail("UpdateLegalRecord", "C1", `{"courtZip":"20001"}`)
+	e.mustInvoke("UpdateLegalRecord", "C1", `{"courtZip":"10002"}`)
+
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("UpdateLegalRecord", "C1", `{"status":"CLOSED"}`)
+}
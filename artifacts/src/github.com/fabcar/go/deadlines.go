@@ -0,0 +1,564 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	deadlineRuleObjectType = "deadlineRule"
+	courtHolidayObjectType = "courtHoliday"
+	caseDeadlineObjectType = "caseDeadline"
+
+	dateLayout = "2006-01-02"
+
+	DeadlineOpen     = "OPEN"
+	DeadlineMet      = "MET"
+	DeadlineUpcoming = "UPCOMING"
+	DeadlineMissed   = "MISSED"
+)
+
+// DeadlineRule sets a deadline Days after an event (e.g. JUDGMENT) in cases of a court. An empty
+// CaseType applies the rule to every case type. With BusinessDays only weekdays that are not court
+// holidays are counted; otherwise a due date falling on a weekend or holiday moves to the next
+// business day.
+type DeadlineRule struct {
+	ID           string `json:"id"`
+	CourtID      string `json:"courtID"`
+	CaseType     string `json:"caseType"`
+	Event        string `json:"event"`
+	Description  string `json:"description"`
+	Days         int    `json:"days"`
+	BusinessDays bool   `json:"businessDays"`
+}
+
+type CourtHoliday struct {
+	CourtID string `json:"courtID"`
+	Date    string `json:"date"`
+	Name    string `json:"name"`
+}
+
+// Deadline is a due date computed for a case from a rule when its event occurred. It is stored as
+// OPEN or MET; queries report open deadlines as UPCOMING or MISSED.
+type Deadline struct {
+	DeadlineID  string `json:"deadlineID"`
+	CaseID      string `json:"caseID"`
+	RuleID      string `json:"ruleID"`
+	Event       string `json:"event"`
+	Description string `json:"description"`
+	EventDate   string `json:"eventDate"`
+	DueDate     string `json:"dueDate"`
+	Status      string `json:"status"`
+	CreatedAt   string `json:"createdAt"`
+	MetBy       string `json:"metBy,omitempty" metadata:"metBy,optional"`
+	MetAt       string `json:"metAt,omitempty" metadata:"metAt,optional"`
+}
+
+// SetDeadlineRule creates or replaces a deadline rule of a court owned by the caller's organization
+func (s *SmartContract) SetDeadlineRule(ctx contractapi.TransactionContextInterface, courtID string, ruleJSON string) error {
+	court, err := getCourtForCalendarChange(ctx, courtID)
+	if err != nil {
+		return err
+	}
+
+	var rule DeadlineRule
+	err = json.Unmarshal([]byte(ruleJSON), &rule)
+	if err != nil {
+		return fmt.Errorf("Failed while unmarshalling deadline rule. %s", err.Error())
+	}
+	if len(rule.ID) == 0 || len(rule.Event) == 0 {
+		return fmt.Errorf("Deadline rule id and event are required")
+	}
+	if rule.Days <= 0 {
+		return fmt.Errorf("Deadline rule %s must allow at least one day", rule.ID)
+	}
+	rule.CourtID = court.ID
+	rule.Event = strings.ToUpper(rule.Event)
+
+	ruleKey, err := ctx.GetStub().CreateCompositeKey(deadlineRuleObjectType, []string{court.ID, rule.ID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	ruleAsBytes, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal deadline rule. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(ruleKey, ruleAsBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to put deadline rule. %s", err.Error())
+	}
+
+	return ctx.GetStub().SetEvent("SetDeadlineRule", ruleAsBytes)
+}
+
+// RemoveDeadlineRule removes a deadline rule. Deadlines already computed from it are kept.
+func (s *SmartContract) RemoveDeadlineRule(ctx contractapi.TransactionContextInterface, courtID string, ruleID string) error {
+	court, err := getCourtForCalendarChange(ctx, courtID)
+	if err != nil {
+		return err
+	}
+
+	ruleKey, err := ctx.GetStub().CreateCompositeKey(deadlineRuleObjectType, []string{court.ID, ruleID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	existing, err := ctx.GetStub().GetState(ruleKey)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing == nil {
+		return fmt.Errorf("Deadline rule %s does not exist for court %s", ruleID, courtID)
+	}
+	err = ctx.GetStub().DelState(ruleKey)
+	if err != nil {
+		return fmt.Errorf("Failed to delete deadline rule. %s", err.Error())
+	}
+
+	return ctx.GetStub().SetEvent("RemoveDeadlineRule", []byte(ruleID))
+}
+
+func (s *SmartContract) GetDeadlineRules(ctx contractapi.TransactionContextInterface, courtID string) ([]*DeadlineRule, error) {
+	if len(courtID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct court id")
+	}
+	return getDeadlineRules(ctx, courtID)
+}
+
+// AddCourtHoliday adds a day (YYYY-MM-DD) on which a court is closed
+func (s *SmartContract) AddCourtHoliday(ctx contractapi.TransactionContextInterface, courtID string, date string, name string) error {
+	court, err := getCourtForCalendarChange(ctx, courtID)
+	if err != nil {
+		return err
+	}
+	_, err = time.Parse(dateLayout, date)
+	if err != nil {
+		return fmt.Errorf("Invalid holiday date %s. %s", date, err.Error())
+	}
+
+	holiday := CourtHoliday{CourtID: court.ID, Date: date, Name: name}
+	holidayKey, err := ctx.GetStub().CreateCompositeKey(courtHolidayObjectType, []string{court.ID, date})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	holidayAsBytes, err := json.Marshal(holiday)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal court holiday. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(holidayKey, holidayAsBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to put court holiday. %s", err.Error())
+	}
+
+	return ctx.GetStub().SetEvent("AddCourtHoliday", holidayAsBytes)
+}
+
+// RemoveCourtHoliday removes a holiday from a court's calendar. Deadlines already computed are kept.
+func (s *SmartContract) RemoveCourtHoliday(ctx contractapi.TransactionContextInterface, courtID string, date string) error {
+	court, err := getCourtForCalendarChange(ctx, courtID)
+	if err != nil {
+		return err
+	}
+
+	holidayKey, err := ctx.GetStub().CreateCompositeKey(courtHolidayObjectType, []string{court.ID, date})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	err = ctx.GetStub().DelState(holidayKey)
+	if err != nil {
+		return fmt.Errorf("Failed to delete court holiday. %s", err.Error())
+	}
+
+	return ctx.GetStub().SetEvent("RemoveCourtHoliday", []byte(date))
+}
+
+// GetCourtHolidays returns the holidays of a court, in date order
+func (s *SmartContract) GetCourtHolidays(ctx contractapi.TransactionContextInterface, courtID string) ([]*CourtHoliday, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(courtHolidayObjectType, []string{courtID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var holidays []*CourtHoliday
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var holiday CourtHoliday
+		err = json.Unmarshal(queryResponse.Value, &holiday)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal court holiday. %s", err.Error())
+		}
+		holidays = append(holidays, &holiday)
+	}
+
+	return holidays, nil
+}
+
+// TriggerDeadlines records that an event occurred in a case on eventDate (RFC 3339 or YYYY-MM-DD,
+// the transaction date when empty) and computes the deadlines of the matching rules. Orders trigger
+// the event named after their type automatically.
+func (s *SmartContract) TriggerDeadlines(ctx contractapi.TransactionContextInterface, caseID string, event string, eventDate string) ([]*Deadline, error) {
+	role, err := requireRole(ctx, "approver", "judge")
+	if err != nil {
+		return nil, err
+	}
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireDocketWriter(ctx, legalRecord, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(eventDate) == 0 {
+		eventDate, err = getTxTimestamp(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	date, err := time.Parse(time.RFC3339, eventDate)
+	if err != nil {
+		date, err = time.Parse(dateLayout, eventDate)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid event date %s", eventDate)
+		}
+	}
+
+	deadlines, err := triggerDeadlines(ctx, legalRecord, event, date)
+	if err != nil {
+		return nil, err
+	}
+
+	deadlinesAsBytes, err := json.Marshal(deadlines)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal deadlines. %s", err.Error())
+	}
+	ctx.GetStub().SetEvent("TriggerDeadlines", deadlinesAsBytes)
+
+	return deadlines, nil
+}
+
+// CompleteDeadline marks an open deadline of a case as met
+func (s *SmartContract) CompleteDeadline(ctx contractapi.TransactionContextInterface, caseID string, deadlineID string) error {
+	role, err := requireRole(ctx, "approver", "judge")
+	if err != nil {
+		return err
+	}
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	err = requireDocketWriter(ctx, legalRecord, role)
+	if err != nil {
+		return err
+	}
+
+	deadlineKey, err := ctx.GetStub().CreateCompositeKey(caseDeadlineObjectType, []string{caseID, deadlineID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	deadlineAsBytes, err := ctx.GetStub().GetState(deadlineKey)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if deadlineAsBytes == nil {
+		return fmt.Errorf("Deadline %s does not exist for %s", deadlineID, caseID)
+	}
+
+	var deadline Deadline
+	err = json.Unmarshal(deadlineAsBytes, &deadline)
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal deadline. %s", err.Error())
+	}
+	if deadline.Status != DeadlineOpen {
+		return fmt.Errorf("Deadline %s has already been met", deadlineID)
+	}
+
+	deadline.Status = DeadlineMet
+	deadline.MetBy, err = getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	deadline.MetAt, err = getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	deadlineAsBytes, err = putDeadline(ctx, &deadline)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("CompleteDeadline", deadlineAsBytes)
+}
+
+// GetCaseDeadlines returns the open deadlines of a case by due date, as UPCOMING or MISSED
+func (s *SmartContract) GetCaseDeadlines(ctx contractapi.TransactionContextInterface, caseID string) ([]*Deadline, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	deadlines, err := getOpenDeadlines(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	sortDeadlines(deadlines)
+	return deadlines, nil
+}
+
+// GetJudgeDeadlines returns the open deadlines of all cases a judge is assigned to, as UPCOMING or MISSED
+func (s *SmartContract) GetJudgeDeadlines(ctx contractapi.TransactionContextInterface, judgeID string) ([]*Deadline, error) {
+	_, err := requireRole(ctx, "approver", "judge")
+	if err != nil {
+		return nil, err
+	}
+	if len(judgeID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct judge id")
+	}
+
+	caseIDs, err := getJudgeCaseIDs(ctx, judgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	deadlines := []*Deadline{}
+	for _, caseID := range caseIDs {
+		// The index is only rewritten when a record is stored, so confirm the assignment
+		legalRecord, err := getLegalRecord(ctx, caseID)
+		if err != nil {
+			return nil, err
+		}
+		if !isJudgeAssigned(legalRecord, judgeID) {
+			continue
+		}
+
+		caseDeadlines, err := getOpenDeadlines(ctx, caseID)
+		if err != nil {
+			return nil, err
+		}
+		deadlines = append(deadlines, caseDeadlines...)
+	}
+
+	sortDeadlines(deadlines)
+	return deadlines, nil
+}
+
+// triggerDeadlines computes and stores the deadlines of the rules of the case's court matching the
+// event and case type
+func triggerDeadlines(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, event string, eventDate time.Time) ([]*Deadline, error) {
+	deadlines := []*Deadline{}
+	if len(legalRecord.CourtID) == 0 {
+		return deadlines, nil
+	}
+	event = strings.ToUpper(event)
+
+	rules, err := getDeadlineRules(ctx, legalRecord.CourtID)
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := getCourtHolidayDates(ctx, legalRecord.CourtID)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+		if rule.Event != event || (len(rule.CaseType) > 0 && !strings.EqualFold(rule.CaseType, legalRecord.CaseType)) {
+			continue
+		}
+
+		deadline := &Deadline{
+			DeadlineID:  ctx.GetStub().GetTxID() + "-" + rule.ID,
+			CaseID:      legalRecord.CaseID,
+			RuleID:      rule.ID,
+			Event:       event,
+			Description: rule.Description,
+			EventDate:   eventDate.UTC().Format(dateLayout),
+			DueDate:     computeDueDate(eventDate.UTC(), rule, holidays).Format(dateLayout),
+			Status:      DeadlineOpen,
+			CreatedAt:   createdAt,
+		}
+		_, err = putDeadline(ctx, deadline)
+		if err != nil {
+			return nil, err
+		}
+		deadlines = append(deadlines, deadline)
+	}
+	return deadlines, nil
+}
+
+// computeDueDate adds the rule's days to the event date. Business days skip weekends and holidays;
+// calendar day deadlines ending on a weekend or holiday move to the next business day.
+func computeDueDate(eventDate time.Time, rule *DeadlineRule, holidays map[string]bool) time.Time {
+	isBusinessDay := func(day time.Time) bool {
+		return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday && !holidays[day.Format(dateLayout)]
+	}
+
+	due := time.Date(eventDate.Year(), eventDate.Month(), eventDate.Day(), 0, 0, 0, 0, time.UTC)
+	if rule.BusinessDays {
+		for counted := 0; counted < rule.Days; {
+			due = due.AddDate(0, 0, 1)
+			if isBusinessDay(due) {
+				counted++
+			}
+		}
+		return due
+	}
+
+	due = due.AddDate(0, 0, rule.Days)
+	for !isBusinessDay(due) {
+		due = due.AddDate(0, 0, 1)
+	}
+	return due
+}
+
+// getOpenDeadlines returns the open deadlines of a case with their status set to UPCOMING or MISSED
+// relative to the transaction date
+func getOpenDeadlines(ctx contractapi.TransactionContextInterface, caseID string) ([]*Deadline, error) {
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	today := now[:len(dateLayout)]
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(caseDeadlineObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	deadlines := []*Deadline{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var deadline Deadline
+		err = json.Unmarshal(queryResponse.Value, &deadline)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal deadline. %s", err.Error())
+		}
+		if deadline.Status != DeadlineOpen {
+			continue
+		}
+		if deadline.DueDate < today {
+			deadline.Status = DeadlineMissed
+		} else {
+			deadline.Status = DeadlineUpcoming
+		}
+		deadlines = append(deadlines, &deadline)
+	}
+
+	return deadlines, nil
+}
+
+func sortDeadlines(deadlines []*Deadline) {
+	sort.Slice(deadlines, func(i, j int) bool {
+		if deadlines[i].DueDate != deadlines[j].DueDate {
+			return deadlines[i].DueDate < deadlines[j].DueDate
+		}
+		return deadlines[i].DeadlineID < deadlines[j].DeadlineID
+	})
+}
+
+// getCourtForCalendarChange loads a court after checking the caller is an approver of its owning organization
+func getCourtForCalendarChange(ctx contractapi.TransactionContextInterface, courtID string) (*Court, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+
+	court, err := getCourt(ctx, courtID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireCourtOwner(ctx, court.OwnerMSP)
+	if err != nil {
+		return nil, err
+	}
+	return court, nil
+}
+
+func getDeadlineRules(ctx contractapi.TransactionContextInterface, courtID string) ([]*DeadlineRule, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(deadlineRuleObjectType, []string{courtID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var rules []*DeadlineRule
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var rule DeadlineRule
+		err = json.Unmarshal(queryResponse.Value, &rule)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal deadline rule. %s", err.Error())
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+func getCourtHolidayDates(ctx contractapi.TransactionContextInterface, courtID string) (map[string]bool, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(courtHolidayObjectType, []string{courtID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	holidays := make(map[string]bool)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to split composite key. %s", err.Error())
+		}
+		holidays[keyParts[1]] = true
+	}
+
+	return holidays, nil
+}
+
+func putDeadline(ctx contractapi.TransactionContextInterface, deadline *Deadline) ([]byte, error) {
+	deadlineKey, err := ctx.GetStub().CreateCompositeKey(caseDeadlineObjectType, []string{deadline.CaseID, deadline.DeadlineID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	deadlineAsBytes, err := json.Marshal(deadline)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal deadline. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(deadlineKey, deadlineAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put deadline. %s", err.Error())
+	}
+	return deadlineAsBytes, nil
+}
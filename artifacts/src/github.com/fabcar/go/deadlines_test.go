@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newDeadlineEnv returns an environment where CT1 closes on 2024-03-05 and 2024-04-01, gives 30
+// calendar days to appeal a judgment, 3 business days to answer a motion in civil cases and 10 days
+// in criminal ones, and has the civil case C1 assigned to judge j. Judge k of CT1 is not assigned to it.
+func newDeadlineEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT1","active":true,"enrollmentID":"k"}`)
+	e.mustInvoke("AddCourtHoliday", "CT1", "2024-03-05", "Court closed")
+	e.mustInvoke("AddCourtHoliday", "CT1", "2024-04-01", "Court closed")
+	e.mustInvoke("SetDeadlineRule", "CT1", `{"id":"appeal","event":"judgment","description":"Notice of appeal","days":30}`)
+	e.mustInvoke("SetDeadlineRule", "CT1", `{"id":"answer","caseType":"civil","event":"MOTION","description":"Answer","days":3,"businessDays":true}`)
+	e.mustInvoke("SetDeadlineRule", "CT1", `{"id":"answer-criminal","caseType":"criminal","event":"MOTION","description":"Answer","days":10}`)
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"C1","courtID":"CT1","caseType":"Civil","judges":["j"],"usersWithAccess":[]}`)
+	return e
+}
+
+func decodeDeadlines(t *testing.T, deadlinesJSON string) []*Deadline {
+	t.Helper()
+	var deadlines []*Deadline
+	err := json.Unmarshal([]byte(deadlinesJSON), &deadlines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deadlines
+}
+
+func TestDeadlineRules(t *testing.T) {
+	e := newTestEnv(t)
+
+	for _, caller := range [][]string{{"Org1MSP", "j", "judge"}, {"Org1MSP", "clerk1", "clerk"}, {"Org2MSP", "admin2", "approver"}} {
+		e.as(caller[0], caller[1], caller[2])
+		e.mustFail("SetDeadlineRule", "CT1", `{"id":"appeal","event":"JUDGMENT","days":30}`)
+		e.mustFail("AddCourtHoliday", "CT1", "2024-12-25", "Christmas")
+	}
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("SetDeadlineRule", "CT9", `{"id":"appeal","event":"JUDGMENT","days":30}`)
+	e.mustFail("SetDeadlineRule", "CT1", `{"id":"appeal","days":30}`)
+	e.mustFail("SetDeadlineRule", "CT1", `{"id":"appeal","event":"JUDGMENT","days":0}`)
+	e.mustInvoke("SetDeadlineRule", "CT1", `{"id":"appeal","event":"judgment","days":30}`)
+	e.mustInvoke("SetDeadlineRule", "CT1", `{"id":"appeal","event":"judgment","days":28,"courtID":"CT2"}`)
+
+	var rules []*DeadlineRule
+	err := json.Unmarshal([]byte(e.mustInvoke("GetDeadlineRules", "CT1")), &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].CourtID != "CT1" || rules[0].Event != "JUDGMENT" || rules[0].Days != 28 {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+	e.mustInvoke("RemoveDeadlineRule", "CT1", "appeal")
+	e.mustFail("RemoveDeadlineRule", "CT1", "appeal")
+
+	e.mustFail("AddCourtHoliday", "CT1", "25/12/2024", "Christmas")
+	e.mustInvoke("AddCourtHoliday", "CT1", "2024-12-25", "Christmas")
+	e.mustInvoke("AddCourtHoliday", "CT1", "2024-01-01", "New Year")
+	var holidays []*CourtHoliday
+	err = json.Unmarshal([]byte(e.mustInvoke("GetCourtHolidays", "CT1")), &holidays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holidays) != 2 || holidays[0].Date != "2024-01-01" || holidays[1].Name != "Christmas" {
+		t.Fatalf("unexpected holidays %+v", holidays)
+	}
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("RemoveCourtHoliday", "CT1", "2024-12-25")
+}
+
+func TestTriggerDeadlines(t *testing.T) {
+	e := newDeadlineEnv(t)
+
+	for _, caller := range [][]string{{"Org2MSP", "admin2", "approver"}, {"Org1MSP", "k", "judge"}, {"Org1MSP", "bob", "client"}} {
+		e.as(caller[0], caller[1], caller[2])
+		e.mustFail("TriggerDeadlines", "C1", "JUDGMENT", "2024-03-01")
+	}
+
+	// A judgment on Friday 2024-03-01 is appealable until Sunday 2024-03-31, moved past the holiday on
+	// Monday to 2024-04-02
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("TriggerDeadlines", "C1", "JUDGMENT", "March 1st")
+	deadlines := decodeDeadlines(t, e.mustInvoke("TriggerDeadlines", "C1", "judgment", "2024-03-01"))
+	if len(deadlines) != 1 || deadlines[0].RuleID != "appeal" || deadlines[0].EventDate != "2024-03-01" || deadlines[0].DueDate != "2024-04-02" || deadlines[0].Status != DeadlineOpen {
+		t.Fatalf("unexpected deadlines %+v", deadlines)
+	}
+
+	// Three business days from Friday skip the weekend and the holiday on Tuesday
+	e.as("Org1MSP", "admin1", "approver")
+	deadlines = decodeDeadlines(t, e.mustInvoke("TriggerDeadlines", "C1", "MOTION", "2024-03-01T22:30:00Z"))
+	if len(deadlines) != 1 || deadlines[0].RuleID != "answer" || deadlines[0].DueDate != "2024-03-07" {
+		t.Fatalf("unexpected deadlines %+v", deadlines)
+	}
+	if deadlines := decodeDeadlines(t, e.mustInvoke("TriggerDeadlines", "C1", "HEARING", "2024-03-01")); len(deadlines) != 0 {
+		t.Fatalf("unexpected deadlines %+v", deadlines)
+	}
+
+	// Deadlines are reported by due date, relative to the transaction date
+	e.mustInvoke("TriggerDeadlines", "C1", "JUDGMENT", "2999-01-04")
+	deadlines = decodeDeadlines(t, e.mustInvoke("GetCaseDeadlines", "C1"))
+	if len(deadlines) != 3 || deadlines[0].DueDate != "2024-03-07" || deadlines[0].Status != DeadlineMissed || deadlines[1].Status != DeadlineMissed || deadlines[2].DueDate != "2999-02-04" || deadlines[2].Status != DeadlineUpcoming {
+		t.Fatalf("unexpected deadlines %+v", deadlines)
+	}
+}
+
+func TestCompleteDeadline(t *testing.T) {
+	e := newDeadlineEnv(t)
+	e.as("Org1MSP", "j", "judge")
+	deadline := decodeDeadlines(t, e.mustInvoke("TriggerDeadlines", "C1", "JUDGMENT", "2024-03-01"))[0]
+
+	for _, caller := range [][]string{{"Org2MSP", "admin2", "approver"}, {"Org1MSP", "k", "judge"}, {"Org1MSP", "bob", "client"}} {
+		e.as(caller[0], caller[1], caller[2])
+		e.mustFail("CompleteDeadline", "C1", deadline.DeadlineID)
+	}
+
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("CompleteDeadline", "C1", "tx9999-appeal")
+	e.mustInvoke("CompleteDeadline", "C1", deadline.DeadlineID)
+	e.mustFail("CompleteDeadline", "C1", deadline.DeadlineID)
+	if deadlines := decodeDeadlines(t, e.mustInvoke("GetCaseDeadlines", "C1")); len(deadlines) != 0 {
+		t.Fatalf("unexpected deadlines %+v", deadlines)
+	}
+}
+
+func TestGetJudgeDeadlines(t *testing.T) {
+	e := newDeadlineEnv(t)
+	e.mustInvoke("CreateLegalRecord", `{"caseID":"C2","courtID":"CT1","caseType":"criminal","judges":["j","k"],"usersWithAccess":[]}`)
+
+	// Issuing a judgment triggers its deadlines
+	e.as("Org1MSP", "j", "judge")
+	e.mustInvoke("IssueOrder", "C1", orderJSON("judgment", ""))
+	e.mustInvoke("TriggerDeadlines", "C2", "MOTION", "2024-03-01")
+
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("GetJudgeDeadlines", "j")
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("GetJudgeDeadlines", "")
+
+	deadlines := decodeDeadlines(t, e.mustInvoke("GetJudgeDeadlines", "j"))
+	if len(deadlines) != 2 || deadlines[0].CaseID != "C2" || deadlines[0].DueDate != "2024-03-11" || deadlines[1].CaseID != "C1" || deadlines[1].Event != OrderJudgment || deadlines[1].Status != DeadlineUpcoming {
+		t.Fatalf("unexpected deadlines %+v", deadlines)
+	}
+
+	// Deadlines of cases the judge no longer sits on are left out
+	e.mustInvoke("UnassignJudge", "C2", "j", "recused")
+	e.as("Org1MSP", "k", "judge")
+	if deadlines := decodeDeadlines(t, e.mustInvoke("GetJudgeDeadlines", "j")); len(deadlines) != 1 || deadlines[0].CaseID != "C1" {
+		t.Fatalf("unexpected deadlines %+v", deadlines)
+	}
+	if deadlines := decodeDeadlines(t, e.mustInvoke("GetJudgeDeadlines", "k")); len(deadlines) != 1 || deadlines[0].CaseID != "C2" {
+		t.Fatalf("unexpected deadlines %+v", deadlines)
+	}
+}
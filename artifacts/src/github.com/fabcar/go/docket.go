@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docketEntryObjectType    = "docketEntry"
+	docketSequenceObjectType = "docketSequence"
+
+	defaultDocketPageSize = 25
+
+	DocketFiling = "FILING"
+	DocketOrder  = "ORDER"
+	DocketMinute = "MINUTE"
+	DocketNotice = "NOTICE"
+)
+
+// DocketEntry is an immutable entry in the chronological docket of a case. Entries are numbered
+// from 1 in the order they are added and are never changed or deleted.
+type DocketEntry struct {
+	CaseID      string `json:"caseID"`
+	Number      int    `json:"number"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	DocumentRef string `json:"documentRef,omitempty" metadata:"documentRef,optional"`
+	Sealed      bool   `json:"sealed"`
+	FiledBy     string `json:"filedBy"`
+	FilerRole   string `json:"filerRole"`
+	FiledAt     string `json:"filedAt"`
+	TxID        string `json:"txID"`
+}
+
+// DocketPage is one page of a docket. Bookmark is the number of the next entry to read and is
+// empty once the end of the docket has been reached.
+type DocketPage struct {
+	Entries  []*DocketEntry `json:"entries"`
+	Bookmark string         `json:"bookmark"`
+}
+
+// AddDocketEntry appends an entry to the docket of a case. It can be called by an approver of the
+// owning court or by a judge assigned to the case.
+func (s *SmartContract) AddDocketEntry(ctx contractapi.TransactionContextInterface, caseID string, entryJSON string) (*DocketEntry, error) {
+	role, err := requireRole(ctx, "approver", "judge")
+	if err != nil {
+		return nil, err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireDocketWriter(ctx, legalRecord, role)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry DocketEntry
+	err = json.Unmarshal([]byte(entryJSON), &entry)
+	if err != nil {
+		return nil, fmt.Errorf("Failed while unmarshalling docket entry. %s", err.Error())
+	}
+	entry.Type = strings.ToUpper(entry.Type)
+	switch entry.Type {
+	case DocketFiling, DocketOrder, DocketMinute, DocketNotice:
+	default:
+		return nil, fmt.Errorf("Invalid docket entry type: %s", entry.Type)
+	}
+	if len(entry.Description) == 0 {
+		return nil, fmt.Errorf("Docket entry description is required")
+	}
+
+	entryAsBytes, err := appendDocketEntry(ctx, caseID, &entry)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.GetStub().SetEvent("AddDocketEntry", entryAsBytes)
+
+	return &entry, nil
+}
+
+// GetDocket returns a page of the docket of a case, starting at the entry numbered bookmark (or the
+// first entry when bookmark is empty). Sealed entries are only returned to judges assigned to the
+// case and approvers of the owning organization, and the docket of a non-public case only to callers
+// with access to it.
+func (s *SmartContract) GetDocket(ctx contractapi.TransactionContextInterface, caseID string, pageSize int, bookmark string) (*DocketPage, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	privileged, err := requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	if pageSize <= 0 {
+		pageSize = defaultDocketPageSize
+	}
+	start := 1
+	if len(bookmark) > 0 {
+		start, err = strconv.Atoi(bookmark)
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("Invalid docket bookmark: %s", bookmark)
+		}
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(docketEntryObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	page := &DocketPage{Entries: []*DocketEntry{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var entry DocketEntry
+		err = json.Unmarshal(queryResponse.Value, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal docket entry. %s", err.Error())
+		}
+		// Entries hidden from the caller do not count towards the page
+		if entry.Number < start || entry.Sealed && !privileged {
+			continue
+		}
+		if len(page.Entries) == pageSize {
+			page.Bookmark = strconv.Itoa(entry.Number)
+			break
+		}
+		page.Entries = append(page.Entries, &entry)
+	}
+
+	return page, nil
+}
+
+// requireCaseReader checks that the caller may read the docket and orders of a case. It reports
+// whether the caller is a judge assigned to the case or an approver of the owning organization, who
+// may also see sealed entries.
+func requireCaseReader(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) (bool, error) {
+	custodian, err := isRecordCustodian(ctx, legalRecord)
+	if err != nil || custodian {
+		return custodian, err
+	}
+	recordView, err := getRecordView(ctx, legalRecord)
+	if err != nil {
+		return false, err
+	}
+	if recordView == RecordViewSummary {
+		return false, fmt.Errorf("You are not authorized to read the docket and orders of %s", legalRecord.CaseID)
+	}
+	return false, nil
+}
+
+// requireDocketWriter checks that the caller is an approver of the owning court or a judge assigned to the case
+func requireDocketWriter(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, role string) error {
+	if role == "judge" {
+		_, err := getAssignedJudgeForClient(ctx, legalRecord)
+		return err
+	}
+	if len(legalRecord.OwnerMSP) > 0 {
+		return requireCourtOwner(ctx, legalRecord.OwnerMSP)
+	}
+	return nil
+}
+
+// getAssignedJudgeForClient returns the judge assigned to the case that is bound to the calling identity
+func getAssignedJudgeForClient(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) (*Judge, error) {
+	for _, judgeID := range legalRecord.Judges {
+		judge, err := getJudgeForClient(ctx, judgeID)
+		if err == nil {
+			return judge, nil
+		}
+	}
+	return nil, fmt.Errorf("Client identity is not bound to a judge assigned to %s", legalRecord.CaseID)
+}
+
+// appendDocketEntry adds an entry to the docket of a case. It fails with a LegalHoldError while the
+// case is under legal hold.
+func appendDocketEntry(ctx contractapi.TransactionContextInterface, caseID string, entry *DocketEntry) ([]byte, error) {
+	err := requireNoLegalHold(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	return writeDocketEntry(ctx, caseID, entry)
+}
+
+// writeDocketEntry numbers an entry with the next docket sequence of the case, stamps it with the
+// caller and transaction, and writes it. Entry keys are zero padded so they sort by number. Only the
+// notices of placing and releasing a legal hold are written without going through appendDocketEntry.
+func writeDocketEntry(ctx contractapi.TransactionContextInterface, caseID string, entry *DocketEntry) ([]byte, error) {
+	sequenceKey, err := ctx.GetStub().CreateCompositeKey(docketSequenceObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	sequenceAsBytes, err := ctx.GetStub().GetState(sequenceKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	last := 0
+	if sequenceAsBytes != nil {
+		last, err = strconv.Atoi(string(sequenceAsBytes))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid docket sequence for %s. %s", caseID, err.Error())
+		}
+	}
+
+	filedBy, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filerRole, err := getClientRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.CaseID = caseID
+	entry.Number = last + 1
+	entry.FiledBy = filedBy
+	entry.FilerRole = filerRole
+	entry.FiledAt = filedAt
+	entry.TxID = ctx.GetStub().GetTxID()
+
+	entryKey, err := ctx.GetStub().CreateCompositeKey(docketEntryObjectType, []string{caseID, fmt.Sprintf("%010d", entry.Number)})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	existing, err := ctx.GetStub().GetState(entryKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("Docket entry %d already exists for %s", entry.Number, caseID)
+	}
+
+	entryAsBytes, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal docket entry. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(entryKey, entryAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put docket entry. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(sequenceKey, []byte(strconv.Itoa(entry.Number)))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put docket sequence. %s", err.Error())
+	}
+	return entryAsBytes, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func getDocketPage(e *testEnv, caseID string, pageSize string, bookmark string) *DocketPage {
+	e.t.Helper()
+	page := new(DocketPage)
+	err := json.Unmarshal([]byte(e.mustInvoke("GetDocket", caseID, pageSize, bookmark)), page)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return page
+}
+
+func docketNumbers(page *DocketPage) []int {
+	numbers := []int{}
+	for _, entry := range page.Entries {
+		numbers = append(numbers, entry.Number)
+	}
+	return numbers
+}
+
+func TestAddDocketEntry(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT1","active":true,"enrollmentID":"k"}`)
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{"j"}}))
+
+	e.mustFail("AddDocketEntry", "C1", `{"type":"memo","description":"Note"}`)
+	e.mustFail("AddDocketEntry", "C1", `{"type":"minute"}`)
+	first := e.mustInvoke("AddDocketEntry", "C1", `{"type":"minute","description":"Status conference","number":7,"filedBy":"someone"}`)
+	if jsonField(t, first, "number") != "1" || jsonField(t, first, "filedBy") != "admin1" || jsonField(t, first, "filerRole") != "approver" {
+		t.Fatal(first)
+	}
+
+	e.as("Org1MSP", "j", "judge")
+	if second := e.mustInvoke("AddDocketEntry", "C1", `{"type":"order","description":"Scheduling order"}`); jsonField(t, second, "number") != "2" {
+		t.Fatal(second)
+	}
+
+	// Only the owning organization and the assigned judges write the docket
+	e.as("Org1MSP", "k", "judge")
+	e.mustFail("AddDocketEntry", "C1", `{"type":"minute","description":"Note"}`)
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("AddDocketEntry", "C1", `{"type":"minute","description":"Note"}`)
+	e.as("Org1MSP", "clerk1", "clerk")
+	e.mustFail("AddDocketEntry", "C1", `{"type":"minute","description":"Note"}`)
+}
+
+func TestGetDocketPages(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}, "usersWithAccess": []string{"alice"}}))
+	for _, sealed := range []bool{false, true, true, false, false} {
+		e.mustInvoke("AddDocketEntry", "C1", toJSON(t, map[string]interface{}{"type": "minute", "description": "Entry", "sealed": sealed}))
+	}
+
+	page := getDocketPage(e, "C1", "2", "")
+	if numbers := docketNumbers(page); len(numbers) != 2 || numbers[1] != 2 || page.Bookmark != "3" {
+		t.Fatalf("approver got %v, bookmark %q", numbers, page.Bookmark)
+	}
+
+	// Sealed entries hidden from the caller do not shorten the page
+	e.as("Org2MSP", "alice", "client")
+	page = getDocketPage(e, "C1", "2", "")
+	if numbers := docketNumbers(page); len(numbers) != 2 || numbers[0] != 1 || numbers[1] != 4 || page.Bookmark != "5" {
+		t.Fatalf("first page %v, bookmark %q", numbers, page.Bookmark)
+	}
+	page = getDocketPage(e, "C1", "2", page.Bookmark)
+	if numbers := docketNumbers(page); len(numbers) != 1 || numbers[0] != 5 || page.Bookmark != "" {
+		t.Fatalf("second page %v, bookmark %q", numbers, page.Bookmark)
+	}
+	e.mustFail("GetDocket", "C1", "2", "0")
+
+	e.as("Org2MSP", "mallory", "client")
+	e.mustFail("GetDocket", "C1", "2", "")
+}
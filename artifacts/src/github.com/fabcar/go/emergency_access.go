@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	emergencyAccessObjectType       = "emergencyAccess"
+	emergencyAccessReviewObjectType = "emergencyAccessReview"
+	emergencyReviewFlagObjectType   = "emergencyReviewFlag"
+
+	// emergencyAccessRole is the only role allowed to break the glass on a restricted record
+	emergencyAccessRole = "officer"
+)
+
+// EmergencyAccess is the audit entry written for every break-glass read. It is never modified;
+// the approver's review is stored separately as an EmergencyAccessReview.
+type EmergencyAccess struct {
+	AccessID   string `json:"accessID"`
+	CaseID     string `json:"caseID"`
+	Officer    string `json:"officer"`
+	Role       string `json:"role"`
+	Reason     string `json:"reason"`
+	AccessedAt string `json:"accessedAt"`
+	Priority   string `json:"priority"`
+}
+
+type EmergencyAccessReview struct {
+	AccessID   string `json:"accessID"`
+	CaseID     string `json:"caseID"`
+	ReviewedBy string `json:"reviewedBy"`
+	ReviewedAt string `json:"reviewedAt"`
+	Findings   string `json:"findings"`
+}
+
+// EmergencyAccessReport pairs an emergency access with its review, if any
+type EmergencyAccessReport struct {
+	Access *EmergencyAccess       `json:"access"`
+	Review *EmergencyAccessReview `json:"review"`
+}
+
+// EmergencyAccessLegalRecord returns a restricted legal record to an officer regardless of UsersWithAccess.
+// The full, unredacted record is returned, also on a case under legal hold. It must be submitted (not
+// evaluated) so that the audit entry and review flag are committed. The review flag is kept under its
+// own key so that the legal record itself is not changed.
+func (s *SmartContract) EmergencyAccessLegalRecord(ctx contractapi.TransactionContextInterface, caseID string, reason string) (*LegalRecord, error) {
+	role, err := requireRole(ctx, emergencyAccessRole)
+	if err != nil {
+		return nil, err
+	}
+	if len(reason) == 0 {
+		return nil, fmt.Errorf("Please pass a reason for emergency access")
+	}
+
+	officer, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accessedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := EmergencyAccess{
+		AccessID:   ctx.GetStub().GetTxID(),
+		CaseID:     caseID,
+		Officer:    officer,
+		Role:       role,
+		Reason:     reason,
+		AccessedAt: accessedAt,
+		Priority:   "HIGH",
+	}
+
+	accessKey, err := ctx.GetStub().CreateCompositeKey(emergencyAccessObjectType, []string{caseID, access.AccessID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	accessAsBytes, err := json.Marshal(access)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal emergency access. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(accessKey, accessAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put emergency access. %s", err.Error())
+	}
+
+	flagKey, err := ctx.GetStub().CreateCompositeKey(emergencyReviewFlagObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(flagKey, []byte(access.AccessID))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put emergency review flag. %s", err.Error())
+	}
+
+	logger.Warningf("Emergency access to %s by %s: %s", caseID, officer, reason)
+	ctx.GetStub().SetEvent("EmergencyAccessLegalRecord", accessAsBytes)
+
+	return withPrivateDetails(ctx, legalRecord)
+}
+
+// ReviewEmergencyAccess records an approver's post-hoc review of an emergency access. The case
+// review flag is cleared once every emergency access on it has been reviewed.
+func (s *SmartContract) ReviewEmergencyAccess(ctx contractapi.TransactionContextInterface, caseID string, accessID string, findings string) error {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return fmt.Errorf("Please pass the review findings")
+	}
+
+	reports, err := getEmergencyAccessReports(ctx, caseID)
+	if err != nil {
+		return err
+	}
+
+	var target *EmergencyAccessReport
+	outstanding := 0
+	for _, report := range reports {
+		if report.Access.AccessID == accessID {
+			target = report
+		} else if report.Review == nil {
+			outstanding++
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("Emergency access %s does not exist for %s", accessID, caseID)
+	}
+	if target.Review != nil {
+		return fmt.Errorf("Emergency access %s has already been reviewed", accessID)
+	}
+
+	reviewedBy, err := getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	reviewedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	review := EmergencyAccessReview{
+		AccessID:   accessID,
+		CaseID:     caseID,
+		ReviewedBy: reviewedBy,
+		ReviewedAt: reviewedAt,
+		Findings:   findings,
+	}
+
+	reviewKey, err := ctx.GetStub().CreateCompositeKey(emergencyAccessReviewObjectType, []string{caseID, accessID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	reviewAsBytes, err := json.Marshal(review)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal emergency access review. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(reviewKey, reviewAsBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to put emergency access review. %s", err.Error())
+	}
+
+	if outstanding == 0 {
+		flagKey, err := ctx.GetStub().CreateCompositeKey(emergencyReviewFlagObjectType, []string{caseID})
+		if err != nil {
+			return fmt.Errorf("Failed to create composite key. %s", err.Error())
+		}
+		err = ctx.GetStub().DelState(flagKey)
+		if err != nil {
+			return fmt.Errorf("Failed to delete emergency review flag. %s", err.Error())
+		}
+	}
+
+	return ctx.GetStub().SetEvent("ReviewEmergencyAccess", reviewAsBytes)
+}
+
+// QueryEmergencyAccesses lists the emergency accesses on a case, or on all cases when caseID is empty
+func (s *SmartContract) QueryEmergencyAccesses(ctx contractapi.TransactionContextInterface, caseID string) ([]*EmergencyAccessReport, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+
+	return getEmergencyAccessReports(ctx, caseID)
+}
+
+// QueryCasesFlaggedForReview lists the cases with emergency accesses that have not been reviewed yet
+func (s *SmartContract) QueryCasesFlaggedForReview(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(emergencyReviewFlagObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	caseIDs := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to split composite key. %s", err.Error())
+		}
+		caseIDs = append(caseIDs, keyParts[0])
+	}
+	return caseIDs, nil
+}
+
+func getEmergencyAccessReports(ctx contractapi.TransactionContextInterface, caseID string) ([]*EmergencyAccessReport, error) {
+	var keys []string
+	if len(caseID) > 0 {
+		keys = []string{caseID}
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(emergencyAccessObjectType, keys)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var reports []*EmergencyAccessReport
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		access := new(EmergencyAccess)
+		err = json.Unmarshal(queryResponse.Value, access)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal emergency access. %s", err.Error())
+		}
+
+		reviewKey, err := ctx.GetStub().CreateCompositeKey(emergencyAccessReviewObjectType, []string{access.CaseID, access.AccessID})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+		}
+		reviewAsBytes, err := ctx.GetStub().GetState(reviewKey)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+		}
+
+		report := &EmergencyAccessReport{Access: access}
+		if reviewAsBytes != nil {
+			report.Review = new(EmergencyAccessReview)
+			err = json.Unmarshal(reviewAsBytes, report.Review)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to unmarshal emergency access review. %s", err.Error())
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newEmergencyEnv returns an environment with the sealed case C1 of CT1, whose proceedings are only
+// readable by admin1
+func newEmergencyEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.setPrivateDetails(map[string]string{"description": "Sealed matter", "proceedings": "/sealed/c1.pdf"})
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":          "C1",
+		"courtID":         "CT1",
+		"judges":          []string{},
+		"confidentiality": "SEALED",
+		"usersWithAccess": []string{"admin1"},
+	}))
+	e.setPrivateDetails(map[string]string{})
+	return e
+}
+
+func queryEmergencyAccesses(e *testEnv, caseID string) []*EmergencyAccessReport {
+	e.t.Helper()
+	var reports []*EmergencyAccessReport
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryEmergencyAccesses", caseID)), &reports)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return reports
+}
+
+func TestEmergencyAccessLegalRecord(t *testing.T) {
+	e := newEmergencyEnv(t)
+
+	for _, role := range []string{"approver", "judge", "lawyer", "client"} {
+		e.as("Org1MSP", "eve", role)
+		e.mustFail("EmergencyAccessLegalRecord", "C1", "imminent harm")
+	}
+	e.as("Org1MSP", "off", "officer")
+	e.mustFail("EmergencyAccessLegalRecord", "C1", "")
+	e.mustFail("EmergencyAccessLegalRecord", "C9", "imminent harm")
+
+	legalRecord := e.mustInvoke("EmergencyAccessLegalRecord", "C1", "imminent harm")
+	if jsonField(t, legalRecord, "proceedings") != "/sealed/c1.pdf" {
+		t.Fatalf("emergency access returned a redacted record %s", legalRecord)
+	}
+
+	// Officers of another organization can break the glass as well, and are audited the same way
+	e.as("Org2MSP", "off2", "officer")
+	e.mustInvoke("EmergencyAccessLegalRecord", "C1", "hostage situation")
+
+	e.as("Org1MSP", "admin1", "approver")
+	reports := queryEmergencyAccesses(e, "C1")
+	if len(reports) != 2 || reports[0].Access.Officer != "off" || reports[0].Access.Reason != "imminent harm" || reports[0].Access.Priority != "HIGH" || reports[1].Access.Officer != "off2" {
+		t.Fatalf("unexpected emergency accesses %+v", reports)
+	}
+	for _, report := range reports {
+		if report.Review != nil {
+			t.Fatalf("emergency access already reviewed %+v", report)
+		}
+	}
+}
+
+func TestReviewEmergencyAccess(t *testing.T) {
+	e := newEmergencyEnv(t)
+	e.as("Org1MSP", "off", "officer")
+	e.mustInvoke("EmergencyAccessLegalRecord", "C1", "imminent harm")
+	e.mustInvoke("EmergencyAccessLegalRecord", "C1", "follow up")
+
+	e.as("Org1MSP", "admin1", "approver")
+	reports := queryEmergencyAccesses(e, "C1")
+	if len(reports) != 2 {
+		t.Fatalf("unexpected emergency accesses %+v", reports)
+	}
+	first, second := reports[0].Access.AccessID, reports[1].Access.AccessID
+
+	e.as("Org1MSP", "off", "officer")
+	e.mustFail("ReviewEmergencyAccess", "C1", first, "justified")
+	e.mustFail("QueryEmergencyAccesses", "C1")
+	e.mustFail("QueryCasesFlaggedForReview")
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("ReviewEmergencyAccess", "C1", first, "")
+	e.mustFail("ReviewEmergencyAccess", "C1", "tx9999", "justified")
+	e.mustInvoke("ReviewEmergencyAccess", "C1", first, "justified")
+	e.mustFail("ReviewEmergencyAccess", "C1", first, "again")
+
+	// The case stays flagged until every access has been reviewed
+	if flagged := e.mustInvoke("QueryCasesFlaggedForReview"); flagged != `["C1"]` {
+		t.Fatalf("unexpected flagged cases %s", flagged)
+	}
+	e.mustInvoke("ReviewEmergencyAccess", "C1", second, "justified")
+	if flagged := e.mustInvoke("QueryCasesFlaggedForReview"); flagged != `[]` {
+		t.Fatalf("unexpected flagged cases %s", flagged)
+	}
+
+	reports = queryEmergencyAccesses(e, "")
+	if len(reports) != 2 || reports[0].Review == nil || reports[0].Review.ReviewedBy != "admin1" || reports[0].Review.Findings != "justified" || reports[1].Review == nil {
+		t.Fatalf("unexpected emergency accesses %+v", reports)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// orgEndorsementObjectType keys hold one entry per organization whose key-level endorsement policy
+// is that organization alone. Writing one of them proves the organization endorsed the transaction.
+const orgEndorsementObjectType = "orgEndorsement"
+
+type OrgEndorsement struct {
+	MSPID     string `json:"mspID"`
+	LastTxID  string `json:"lastTxID"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// setRecordEndorsementPolicy requires the peers of the owning court organization, and of the
+// supervising organization when there is one, to endorse every later write to the legal record
+func setRecordEndorsementPolicy(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) error {
+	policy, err := endorsementPolicyForOrgs(legalRecord.OwnerMSP, legalRecord.SupervisorMSP)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().SetStateValidationParameter(legalRecord.CaseID, policy)
+	if err != nil {
+		return fmt.Errorf("Failed to set endorsement policy for %s. %s", legalRecord.CaseID, err.Error())
+	}
+	return nil
+}
+
+func endorsementPolicyForOrgs(mspIDs ...string) ([]byte, error) {
+	endorsementPolicy, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create endorsement policy. %s", err.Error())
+	}
+
+	for _, mspID := range mspIDs {
+		if len(mspID) == 0 {
+			continue
+		}
+		err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, mspID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to add %s to endorsement policy. %s", mspID, err.Error())
+		}
+	}
+
+	policy, err := endorsementPolicy.Policy()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize endorsement policy. %s", err.Error())
+	}
+	return policy, nil
+}
+
+// createOrgEndorsementKey creates the endorsement key of an organization with a key-level policy of
+// that organization alone. It is called when the organization registers a court, so the key and its
+// policy exist before any transaction relies on them.
+func createOrgEndorsementKey(ctx contractapi.TransactionContextInterface, mspID string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(orgEndorsementObjectType, []string{mspID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return nil
+	}
+
+	err = putOrgEndorsement(ctx, key, mspID)
+	if err != nil {
+		return err
+	}
+	policy, err := endorsementPolicyForOrgs(mspID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetStateValidationParameter(key, policy)
+	if err != nil {
+		return fmt.Errorf("Failed to set endorsement policy for %s. %s", mspID, err.Error())
+	}
+	return nil
+}
+
+// touchOrgEndorsementKey writes the organization's endorsement key so the transaction only validates
+// when that organization's peers endorse it. Organizations without a registered court have no key.
+func touchOrgEndorsementKey(ctx contractapi.TransactionContextInterface, mspID string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(orgEndorsementObjectType, []string{mspID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing == nil {
+		return fmt.Errorf("%s has no endorsement key; it must register a court first", mspID)
+	}
+	return putOrgEndorsement(ctx, key, mspID)
+}
+
+func putOrgEndorsement(ctx contractapi.TransactionContextInterface, key string, mspID string) error {
+	updatedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	orgEndorsement := OrgEndorsement{MSPID: mspID, LastTxID: ctx.GetStub().GetTxID(), UpdatedAt: updatedAt}
+	orgEndorsementAsBytes, err := json.Marshal(orgEndorsement)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal org endorsement. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(key, orgEndorsementAsBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to put org endorsement. %s", err.Error())
+	}
+	return nil
+}
+
+// moveLegalRecordToCourt puts a legal record before another court: judges of the old court are
+// unassigned, and court, zip code, owner and private collection change together. The new owner's
+// endorsement key is written so its peers must endorse. The caller still has to put the record and
+// reset its endorsement policy.
+func moveLegalRecordToCourt(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, court *Court, zip string, reason string) error {
+	for _, judgeID := range append([]string{}, legalRecord.Judges...) {
+		err := unassignJudge(ctx, legalRecord, judgeID, JudgeUnassigned, reason)
+		if err != nil {
+			return err
+		}
+	}
+
+	legalRecord.CourtID = court.ID
+	legalRecord.CourtType = court.Type
+	legalRecord.CourtCategory = court.Category
+	legalRecord.CourtZip = zip
+	legalRecord.OwnerMSP = court.OwnerMSP
+
+	err := touchOrgEndorsementKey(ctx, court.OwnerMSP)
+	if err != nil {
+		return err
+	}
+	return moveLegalRecordPrivateDetails(ctx, legalRecord)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+)
+
+// endorsingOrgs returns the organizations of the key-level endorsement policy of a key
+func endorsingOrgs(e *testEnv, key string) []string {
+	e.t.Helper()
+	policy := e.stub.EndorsementPolicies[""][key]
+	if len(policy) == 0 {
+		return nil
+	}
+	endorsementPolicy, err := statebased.NewStateEP(policy)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	orgs := endorsementPolicy.ListOrgs()
+	sort.Strings(orgs)
+	return orgs
+}
+
+func TestOrgEndorsementKeys(t *testing.T) {
+	e := newTestEnv(t)
+	for _, mspID := range []string{"Org1MSP", "Org2MSP"} {
+		key, err := e.stub.CreateCompositeKey(orgEndorsementObjectType, []string{mspID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if orgs := endorsingOrgs(e, key); len(orgs) != 1 || orgs[0] != mspID {
+			t.Errorf("endorsement key of %s has policy %v", mspID, orgs)
+		}
+	}
+}
+
+func TestRecordEndorsementPolicy(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}}))
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C2", "courtID": "CT1", "judges": []string{}, "supervisorMSP": "Org2MSP"}))
+
+	if orgs := endorsingOrgs(e, "C1"); len(orgs) != 1 || orgs[0] != "Org1MSP" {
+		t.Fatalf("C1 has policy %v", orgs)
+	}
+	if orgs := endorsingOrgs(e, "C2"); len(orgs) != 2 || orgs[0] != "Org1MSP" || orgs[1] != "Org2MSP" {
+		t.Fatalf("C2 has policy %v", orgs)
+	}
+
+	// Neither the supervising organization nor any other changes the record or moves it on its own
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("UpdateLegalRecord", "C2", `{"status":"OPEN"}`)
+	e.mustFail("InitiateCaseTransfer", "C2", "CT2", "", "venue")
+	e.mustFail("ChangeRecordOwner", "C2", "CT2", "Org2MSP")
+}
+
+func TestCaseTransferMovesEndorsementPolicy(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}, "supervisorMSP": "Org3MSP"}))
+	transferID := e.mustInvoke("InitiateCaseTransfer", "C1", "CT2", "", "venue")
+	if orgs := endorsingOrgs(e, "C1"); len(orgs) != 2 || orgs[0] != "Org1MSP" {
+		t.Fatalf("pending transfer changed the policy to %v", orgs)
+	}
+
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("AcceptCaseTransfer", "C1", transferID)
+	if orgs := endorsingOrgs(e, "C1"); len(orgs) != 2 || orgs[0] != "Org2MSP" || orgs[1] != "Org3MSP" {
+		t.Fatalf("C1 has policy %v after the transfer", orgs)
+	}
+
+	// Accepting writes the receiving organization's endorsement key, so its peers must endorse
+	key, err := e.stub.CreateCompositeKey(orgEndorsementObjectType, []string{"Org2MSP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txID := jsonField(t, string(e.stub.State[key]), "lastTxID"); txID != fmt.Sprintf("tx%04d", e.txCount) {
+		t.Fatalf("endorsement key of Org2MSP last written by %s", txID)
+	}
+}
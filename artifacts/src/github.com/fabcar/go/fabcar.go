@@ -7,6 +7,7 @@ import (
 	
 	
 	"strings"
+	"time"
 	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/hyperledger/fabric/common/flogging"
@@ -26,54 +27,54 @@ type User struct {
 	Access string `json:"access"`
 }
 
-func (s *SmartContract) CreateUser(ctx contractapi.TransactionContextInterface, userData string) (string, error) {
+func (s *SmartContract) CreateUser(ctx contractapi.TransactionContextInterface, userData string) (*Receipt, error) {
     if len(userData) == 0 {
-        return "", fmt.Errorf("Please pass the correct user data")
+        return nil, fmt.Errorf("Please pass the correct user data")
     }
 
     var user User
     err := json.Unmarshal([]byte(userData), &user)
     if err != nil {
-        return "", fmt.Errorf("Failed while unmarshalling user. %s", err.Error())
+        return nil, fmt.Errorf("Failed while unmarshalling user. %s", err.Error())
     }
 
     // Validate user data (e.g., check if ID is unique, password complexity, etc.)
     // This is a placeholder for additional validation logic
 
-    userAsBytes, err := json.Marshal(user)
+    // Store the user in the ledger in canonical form
+    userAsBytes, err := putCanonicalState(ctx, user.ID, user, ContentUser, user.ID)
     if err != nil {
-        return "", fmt.Errorf("Failed while marshalling user. %s", err.Error())
+        return nil, err
     }
 
     // Set an event for the creation of a new user
     ctx.GetStub().SetEvent("CreateUser", userAsBytes)
 
-    // Store the user in the ledger
-    return ctx.GetStub().GetTxID(), ctx.GetStub().PutState(user.ID, userAsBytes)
+    return newReceipt(ctx, ReceiptUser, user.ID, "", userAsBytes)
 }
 
 // UpdateUser updates an existing user in the ledger
-func (s *SmartContract) UpdateUser(ctx contractapi.TransactionContextInterface, userID string, updateFieldsJSON string) error {
+func (s *SmartContract) UpdateUser(ctx contractapi.TransactionContextInterface, userID string, updateFieldsJSON string) (*Receipt, error) {
     // Retrieve the existing user
     userAsBytes, err := ctx.GetStub().GetState(userID)
     if err != nil {
-        return fmt.Errorf("Failed to get user: %s", err.Error())
+        return nil, fmt.Errorf("Failed to get user: %s", err.Error())
     }
     if userAsBytes == nil {
-        return fmt.Errorf("User does not exist")
+        return nil, fmt.Errorf("User does not exist")
     }
 
     var user User
     err = json.Unmarshal(userAsBytes, &user)
     if err != nil {
-        return fmt.Errorf("Failed to unmarshal user: %s", err.Error())
+        return nil, fmt.Errorf("Failed to unmarshal user: %s", err.Error())
     }
 
     // Unmarshal the update fields JSON into a map
     var updateFields map[string]interface{}
     err = json.Unmarshal([]byte(updateFieldsJSON), &updateFields)
     if err != nil {
-        return fmt.Errorf("Failed to unmarshal update fields: %s", err.Error())
+        return nil, fmt.Errorf("Failed to unmarshal update fields: %s", err.Error())
     }
 
     // Update the user fields
@@ -88,23 +89,17 @@ func (s *SmartContract) UpdateUser(ctx contractapi.TransactionContextInterface,
         case "access":
             user.Access = value.(string)
         default:
-            return fmt.Errorf("Invalid field name: %s", field)
+            return nil, fmt.Errorf("Invalid field name: %s", field)
         }
     }
 
-    // Marshal the updated user back to JSON
-    updatedUserAsBytes, err := json.Marshal(user)
+    // Update the user in the ledger in canonical form
+    updatedUserAsBytes, err := putCanonicalState(ctx, userID, user, ContentUser, userID)
     if err != nil {
-        return fmt.Errorf("Failed to marshal user: %s", err.Error())
+        return nil, err
     }
 
-    // Update the user in the ledger
-    err = ctx.GetStub().PutState(userID, updatedUserAsBytes)
-    if err != nil {
-        return fmt.Errorf("Failed to update user: %s", err.Error())
-    }
-
-    return nil
+    return newReceipt(ctx, ReceiptUser, userID, "", updatedUserAsBytes)
 }
 
 func (s *SmartContract) QueryUser(ctx contractapi.TransactionContextInterface, userID string) (*User, error) {
@@ -158,56 +153,154 @@ func (s *SmartContract) QueryAllUsers(ctx contractapi.TransactionContextInterfac
 }
 
 type LegalRecord struct {
-	CaseID          string   `json:"caseID"`
-	Language        string   `json:"language"`
-	CaseType        string   `json:"caseType"`
-	DateCreated     string   `json:"dateCreated"`
-	CreatedBy       string   `json:"createdBy"`
-	LastUpdated     string   `json:"lastUpdated"`
-	LastUpdatedBy   string   `json:"lastUpdatedBy"`
-	Judges          []string `json:"judges"`
-	CourtType       string   `json:"courtType"`
-	CourtCategory   string   `json:"courtCategory"`
-	CourtZip        string   `json:"courtZip"`
-	Confidentiality string   `json:"confidentiality"`
-	UsersWithAccess []string `json:"usersWithAccess"`
-	Description     string   `json:"description"`
-	Proceedings     string   `json:"proceedings"` // file path
+	CaseID            string        `json:"caseID"`
+	Language          string        `json:"language"`
+	CaseType          string        `json:"caseType"`
+	DateCreated       string        `json:"dateCreated"`
+	CreatedBy         string        `json:"createdBy"`
+	LastUpdated       string        `json:"lastUpdated"`
+	LastUpdatedBy     string        `json:"lastUpdatedBy"`
+	Judges            []string      `json:"judges"`
+	CourtID           string        `json:"courtID"`
+	CourtType         string        `json:"courtType"`
+	CourtCategory     string        `json:"courtCategory"`
+	CourtZip          string        `json:"courtZip"`
+	Confidentiality   string        `json:"confidentiality"`
+	Status            string        `json:"status"`
+	ConsolidatedInto  string        `json:"consolidatedInto,omitempty" metadata:"consolidatedInto,optional"`
+	UsersWithAccess   []string      `json:"usersWithAccess"`
+	Participants      []Participant `json:"participants,omitempty" metadata:"participants,optional"`
+	Description       string        `json:"description"`
+	Proceedings       string        `json:"proceedings"`                                       // file path
+	Documents         []string      `json:"documents,omitempty" metadata:"documents,optional"` // references of documents attached by accepted filings
+	OwnerMSP          string        `json:"ownerMSP"`
+	SupervisorMSP     string        `json:"supervisorMSP"`     // optional second org required to endorse changes
+	PrivateCollection string        `json:"privateCollection"` // holds description and proceedings of non-public records
+	PrivateDataHash   string        `json:"privateDataHash"`
 }
 
-func (s *SmartContract) CreateLegalRecord(ctx contractapi.TransactionContextInterface, legalRecordData string) (string, error) {
+func (s *SmartContract) CreateLegalRecord(ctx contractapi.TransactionContextInterface, legalRecordData string) (*Receipt, error) {
 	value, ok, err := cid.GetAttributeValue(ctx.GetStub(), "role")
     if err != nil {
-        return "", fmt.Errorf("failed while getting attribute. %s", err.Error())
+        return nil, fmt.Errorf("failed while getting attribute. %s", err.Error())
     }
         if !ok {
-        return "", fmt.Errorf("No role attribute found in client identity")
+        return nil, fmt.Errorf("No role attribute found in client identity")
     }
     if value != "approver" {
-        return "", fmt.Errorf("You are not authorized to perform this action")
+        return nil, fmt.Errorf("You are not authorized to perform this action")
     }
 
 	if len(legalRecordData) == 0 {
-		return "", fmt.Errorf("Please pass the correct legal record data")
+		return nil, fmt.Errorf("Please pass the correct legal record data")
 	}
 
 	var legalRecord LegalRecord
 	err2 := json.Unmarshal([]byte(legalRecordData), &legalRecord)
 	if err2 != nil {
-		return "", fmt.Errorf("Failed while unmarshalling legal record. %s", err2.Error())
+		return nil, fmt.Errorf("Failed while unmarshalling legal record. %s", err2.Error())
+	}
+
+	// Every legal record belongs to a registered court and is owned by the court's organization
+	court, err := getCourt(ctx, legalRecord.CourtID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireCourtOwner(ctx, court.OwnerMSP)
+	if err != nil {
+		return nil, err
+	}
+	if len(legalRecord.CourtZip) == 0 {
+		legalRecord.CourtZip = court.ZipCodes[0]
+	}
+	if !court.servesZip(legalRecord.CourtZip) {
+		return nil, fmt.Errorf("Court %s does not serve zip code %s", court.ID, legalRecord.CourtZip)
+	}
+	legalRecord.CourtType = court.Type
+	legalRecord.CourtCategory = court.Category
+	legalRecord.OwnerMSP = court.OwnerMSP
+	// Documents are only attached through accepted filings
+	legalRecord.Documents = nil
+
+	// Records created without a case ID get the next case number of their zip code and case type
+	if len(legalRecord.CaseID) == 0 {
+		legalRecord.CaseID, err = nextCaseNumber(ctx, legalRecord.CourtZip, legalRecord.CaseType)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = requireCaseIDAvailable(ctx, legalRecord.CaseID, court)
+		if err != nil {
+			return nil, err
+		}
+	}
+	existing, err := ctx.GetStub().GetState(legalRecord.CaseID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%s already exists", legalRecord.CaseID)
+	}
+
+	err = validateParticipants(&legalRecord)
+	if err != nil {
+		return nil, err
+	}
+	for i := range legalRecord.Participants {
+		err = requireActiveCounsel(ctx, &legalRecord.Participants[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Initial judges go through the same validation and history as later assignments
+	initialJudges := legalRecord.Judges
+	legalRecord.Judges = []string{}
+	for _, judgeID := range initialJudges {
+		err = assignJudge(ctx, &legalRecord, judgeID, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep description and proceedings of non-public records out of the world state
+	legalRecord.PrivateCollection = privateCollectionName(legalRecord.Confidentiality, legalRecord.OwnerMSP)
+	if len(legalRecord.PrivateCollection) > 0 {
+		err = requireNoPublicPrivateFields(&legalRecord, legalRecord.Description, legalRecord.Proceedings)
+		if err != nil {
+			return nil, err
+		}
+		details := new(LegalRecordPrivateDetails)
+		passed, err := getTransientPrivateDetails(ctx, details)
+		if err != nil {
+			return nil, err
+		}
+		if !passed {
+			return nil, fmt.Errorf("Private details of %s must be passed in the transient field %s", legalRecord.CaseID, legalRecordPrivateTransientKey)
+		}
+		err = putLegalRecordPrivateDetails(ctx, &legalRecord, details)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	legalRecordAsBytes, err := json.Marshal(legalRecord)
+	legalRecordAsBytes, err := putLegalRecord(ctx, &legalRecord)
 	if err != nil {
-		return "", fmt.Errorf("Failed while marshalling legal record. %s", err.Error())
+		return nil, err
 	}
 
 	ctx.GetStub().SetEvent("CreateLegalRecord", legalRecordAsBytes)
 
-	return ctx.GetStub().GetTxID(), ctx.GetStub().PutState(legalRecord.CaseID, legalRecordAsBytes)
+	// Only the owning court (and supervising org) may endorse later changes to the record
+	err = setRecordEndorsementPolicy(ctx, &legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReceipt(ctx, ReceiptLegalRecord, legalRecord.CaseID, "", legalRecordAsBytes)
 }
 
-func (s *SmartContract) UpdateLegalRecord(ctx contractapi.TransactionContextInterface, caseID string, updateFieldsJSON string) error {
+func (s *SmartContract) UpdateLegalRecord(ctx contractapi.TransactionContextInterface, caseID string, updateFieldsJSON string) (*Receipt, error) {
 	// if len(args) != 2 {
 	// 	return fmt.Errorf("Incorrect number of arguments. Expecting 2: caseID and JSON string of fields to update")
 	// }
@@ -218,34 +311,74 @@ func (s *SmartContract) UpdateLegalRecord(ctx contractapi.TransactionContextInte
 	// Retrieve the existing legal record
 	value, ok, err := cid.GetAttributeValue(ctx.GetStub(), "role")
     if err != nil {
-        return fmt.Errorf("failed while getting attribute. %s", err.Error())
+        return nil, fmt.Errorf("failed while getting attribute. %s", err.Error())
     }
         if !ok {
-        return fmt.Errorf("No role attribute found in client identity")
+        return nil, fmt.Errorf("No role attribute found in client identity")
     }
     if value != "approver" {
-        return fmt.Errorf("You are not authorized to perform this action")
+        return nil, fmt.Errorf("You are not authorized to perform this action")
     }
 
 	legalRecordAsBytes, err := ctx.GetStub().GetState(caseID)
 	if err != nil {
-		return fmt.Errorf("Failed to get legal record: %s", err.Error())
+		return nil, fmt.Errorf("Failed to get legal record: %s", err.Error())
 	}
 	if legalRecordAsBytes == nil {
-		return fmt.Errorf("Legal record does not exist")
+		return nil, fmt.Errorf("Legal record does not exist")
 	}
 
 	var legalRecord LegalRecord
 	err = json.Unmarshal(legalRecordAsBytes, &legalRecord)
 	if err != nil {
-		return fmt.Errorf("Failed to unmarshal legal record: %s", err.Error())
+		return nil, fmt.Errorf("Failed to unmarshal legal record: %s", err.Error())
+	}
+
+	// Only the owning court's organization may update the record
+	if len(legalRecord.OwnerMSP) > 0 {
+		err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Unmarshal the update fields JSON into a map
 	var updateFields map[string]interface{}
 	err = json.Unmarshal([]byte(updateFieldsJSON), &updateFields)
 	if err != nil {
-		return fmt.Errorf("Failed to unmarshal update fields: %s", err.Error())
+		return nil, fmt.Errorf("Failed to unmarshal update fields: %s", err.Error())
+	}
+
+	// Description and proceedings of non-public records live in the private data collection and are
+	// only updated from the transient map
+	var privateDetails *LegalRecordPrivateDetails
+	if len(legalRecord.PrivateCollection) > 0 {
+		description, _ := updateFields["description"].(string)
+		proceedings, _ := updateFields["proceedings"].(string)
+		err = requireNoPublicPrivateFields(&legalRecord, description, proceedings)
+		if err != nil {
+			return nil, err
+		}
+		delete(updateFields, "description")
+		delete(updateFields, "proceedings")
+
+		transientMap, err := ctx.GetStub().GetTransient()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get transient data. %s", err.Error())
+		}
+		if _, ok := transientMap[legalRecordPrivateTransientKey]; ok {
+			privateDetails, err = getLegalRecordPrivateDetails(ctx, &legalRecord)
+			if err != nil {
+				return nil, err
+			}
+			if privateDetails == nil {
+				return nil, fmt.Errorf("Private details of %s are not available on this peer", caseID)
+			}
+			_, err = getTransientPrivateDetails(ctx, privateDetails)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Update the legal record fields
@@ -256,50 +389,77 @@ func (s *SmartContract) UpdateLegalRecord(ctx contractapi.TransactionContextInte
 		case "lastUpdatedBy":
 			legalRecord.LastUpdatedBy = value.(string)
 		case "judges":
-			// append to existing judges
-			// judges := value.([]interface{})
-			// var judgesStrings []string
-			// for _, judge := range judges {
-			// 	judgesStrings = append(judgesStrings, judge.(string))
-			// }
-			// legalRecord.Judges = judgesStrings
+			// Append new judges to the existing list after validating them against the registry
 			newJudges := value.([]interface{})
-			// Append new judges to the existing list
 			for _, newJudge := range newJudges {
-				legalRecord.Judges = append(legalRecord.Judges, newJudge.(string))
+				err = assignJudge(ctx, &legalRecord, newJudge.(string), "")
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "courtType", "courtCategory":
+			if len(legalRecord.CourtID) > 0 {
+				return nil, fmt.Errorf("%s is taken from the registered court %s", field, legalRecord.CourtID)
+			}
+			if field == "courtType" {
+				legalRecord.CourtType = value.(string)
+			} else {
+				legalRecord.CourtCategory = value.(string)
 			}
-		case "courtType":
-			legalRecord.CourtType = value.(string)
-		case "courtCategory":
-			legalRecord.CourtCategory = value.(string)
 		case "courtZip":
+			if len(legalRecord.CourtID) > 0 {
+				court, err := getCourt(ctx, legalRecord.CourtID)
+				if err != nil {
+					return nil, err
+				}
+				if !court.servesZip(value.(string)) {
+					return nil, fmt.Errorf("Court %s does not serve zip code %s", court.ID, value.(string))
+				}
+			}
 			legalRecord.CourtZip = value.(string)
 		case "description":
 			legalRecord.Description = value.(string)
 		case "proceedings":
 			legalRecord.Proceedings = value.(string)
+		case "status":
+			status, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("status must be a string")
+			}
+			// Consolidation keeps the previous status of a case, so it can only be set by ConsolidateCases
+			if strings.EqualFold(status, CaseStatusConsolidated) {
+				return nil, fmt.Errorf("Use ConsolidateCases to consolidate cases")
+			}
+			if strings.EqualFold(legalRecord.Status, CaseStatusConsolidated) {
+				return nil, fmt.Errorf("%s is consolidated into %s; use SeverCase to change its status", caseID, legalRecord.ConsolidatedInto)
+			}
+			legalRecord.Status = status
 		default:
-			return fmt.Errorf("Invalid field name: %s", field)
+			return nil, fmt.Errorf("Invalid field name: %s", field)
 		}
 	}
 
-	// Marshal the updated legal record back to JSON
-	updatedLegalRecordAsBytes, err := json.Marshal(legalRecord)
-	if err != nil {
-		return fmt.Errorf("Failed to marshal legal record: %s", err.Error())
+
+	if privateDetails != nil {
+		err = putLegalRecordPrivateDetails(ctx, &legalRecord, privateDetails)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Update the legal record in the ledger
-	err = ctx.GetStub().PutState(caseID, updatedLegalRecordAsBytes)
+	// Update the legal record in the ledger in canonical form
+	updatedLegalRecordAsBytes, err := putLegalRecord(ctx, &legalRecord)
 	if err != nil {
-		return fmt.Errorf("Failed to update legal record: %s", err.Error())
+		return nil, err
 	}
 
-	return nil
+	return newReceipt(ctx, ReceiptLegalRecord, caseID, "", updatedLegalRecordAsBytes)
 }
 
 
-func (s *SmartContract) QueryLegalRecord(ctx contractapi.TransactionContextInterface, caseID string, username string) (*LegalRecord, error) {
+// QueryLegalRecord returns the view of a legal record the calling identity may see. Reads of
+// non-public records are written to the case's access log, so they must be submitted.
+func (s *SmartContract) QueryLegalRecord(ctx contractapi.TransactionContextInterface, caseID string) (*LegalRecord, error) {
     legalRecordAsBytes, err := ctx.GetStub().GetState(caseID)
 
     if err != nil {
@@ -316,27 +476,28 @@ func (s *SmartContract) QueryLegalRecord(ctx contractapi.TransactionContextInter
         return nil, fmt.Errorf("Failed to unmarshal legal record. %s", err.Error())
     }
 
-	hasAccess := false 
-    if strings.EqualFold(legalRecord.Confidentiality, "PUBLIC") {
-        // iterate through the users with access to check if the user has access
-		hasAccess = true
-
-    } else{
-        for _, user := range legalRecord.UsersWithAccess {
-            if strings.EqualFold(user, username) {
-                hasAccess = true
-                break
-            }
-        }
-	}
+    recordView, err := getRecordView(ctx, legalRecord)
+    if err != nil {
+        return nil, err
+    }
 
-    if !hasAccess {
-        return nil, fmt.Errorf("Access Denied: You do not have access to this legal record.")
+    fullRecord, err := withPrivateDetails(ctx, legalRecord)
+    if err != nil {
+        return nil, err
     }
 
-    return legalRecord, nil
+    if !strings.EqualFold(legalRecord.Confidentiality, ConfidentialityPublic) {
+        err = logRecordRead(ctx, caseID, "QueryLegalRecord", recordView)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return redactLegalRecord(fullRecord, recordView), nil
 }
 
+// QueryAllLegalRecords returns the view of every legal record the calling identity may see, logging
+// the reads of non-public records like QueryLegalRecord
 func (s *SmartContract) QueryAllLegalRecords(ctx contractapi.TransactionContextInterface) ([]*LegalRecord, error) {
 	// Start the query with an empty string to get all keys
 	startKey := ""
@@ -348,7 +509,7 @@ func (s *SmartContract) QueryAllLegalRecords(ctx contractapi.TransactionContextI
 	}
 	defer resultsIterator.Close()
 
-	var publicLegalRecords []*LegalRecord
+	var legalRecords []*LegalRecord
 
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
@@ -362,16 +523,115 @@ func (s *SmartContract) QueryAllLegalRecords(ctx contractapi.TransactionContextI
 			return nil, fmt.Errorf("Failed to unmarshal legal record: %s", err.Error())
 		}
 
-		// Check if the legal record is public
-		if strings.EqualFold(legalRecord.Confidentiality, "PUBLIC") {
-			publicLegalRecords = append(publicLegalRecords, &legalRecord)
+		// Skip other entities stored under simple keys, such as users
+		if len(legalRecord.CaseID) == 0 {
+			continue
+		}
+
+		recordView, err := getRecordView(ctx, &legalRecord)
+		if err != nil {
+			return nil, err
+		}
+
+		fullRecord, err := withPrivateDetails(ctx, &legalRecord)
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.EqualFold(legalRecord.Confidentiality, ConfidentialityPublic) {
+			err = logRecordRead(ctx, legalRecord.CaseID, "QueryAllLegalRecords", recordView)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		legalRecords = append(legalRecords, redactLegalRecord(fullRecord, recordView))
+	}
+
+	return legalRecords, nil
+}
+
+// requireRole checks that the client identity carries one of the given role attributes
+func requireRole(ctx contractapi.TransactionContextInterface, roles ...string) (string, error) {
+	value, ok, err := cid.GetAttributeValue(ctx.GetStub(), "role")
+	if err != nil {
+		return "", fmt.Errorf("failed while getting attribute. %s", err.Error())
+	}
+	if !ok {
+		return "", fmt.Errorf("No role attribute found in client identity")
+	}
+	for _, role := range roles {
+		if value == role {
+			return value, nil
 		}
 	}
+	return "", fmt.Errorf("You are not authorized to perform this action")
+}
+
+// getClientName returns the enrollment ID (certificate common name) of the submitting client
+func getClientName(ctx contractapi.TransactionContextInterface) (string, error) {
+	cert, err := cid.GetX509Certificate(ctx.GetStub())
+	if err != nil {
+		return "", fmt.Errorf("Failed to get client certificate. %s", err.Error())
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// getTxTimestamp returns the transaction timestamp in RFC 3339 so that all endorsers agree on it
+func getTxTimestamp(ctx contractapi.TransactionContextInterface) (string, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("Failed to get transaction timestamp. %s", err.Error())
+	}
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC().Format(time.RFC3339), nil
+}
+
+func getLegalRecord(ctx contractapi.TransactionContextInterface, caseID string) (*LegalRecord, error) {
+	legalRecordAsBytes, err := ctx.GetStub().GetState(caseID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if legalRecordAsBytes == nil {
+		return nil, fmt.Errorf("%s does not exist", caseID)
+	}
 
-	return publicLegalRecords, nil
+	legalRecord := new(LegalRecord)
+	err = json.Unmarshal(legalRecordAsBytes, legalRecord)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal legal record. %s", err.Error())
+	}
+	return legalRecord, nil
 }
 
+// putLegalRecord stores a legal record in canonical form and updates the index of cases by judge.
+// It fails with a LegalHoldError while the case is under legal hold.
+func putLegalRecord(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) ([]byte, error) {
+	err := requireNoLegalHold(ctx, legalRecord.CaseID)
+	if err != nil {
+		return nil, err
+	}
+	err = indexJudgeCases(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+	return putCanonicalState(ctx, legalRecord.CaseID, legalRecord, ContentLegalRecord, legalRecord.CaseID)
+}
 
+// hasRecordAccess reports whether username is allowed to read the legal record
+func hasRecordAccess(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, username string) bool {
+	if strings.EqualFold(legalRecord.Confidentiality, "PUBLIC") {
+		return true
+	}
+	if isCounselOfRecord(ctx, legalRecord, username) {
+		return true
+	}
+	for _, user := range legalRecord.UsersWithAccess {
+		if strings.EqualFold(user, username) {
+			return true
+		}
+	}
+	return false
+}
 
 func main() {
 
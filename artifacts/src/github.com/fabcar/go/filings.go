@@ -0,0 +1,518 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	filingObjectType   = "filing"
+	documentObjectType = "document"
+
+	// clerkRole reviews filings submitted to the courts of its organization
+	clerkRole = "clerk"
+
+	FilingDraft     = "DRAFT"
+	FilingSubmitted = "SUBMITTED"
+	FilingAccepted  = "ACCEPTED"
+	FilingRejected  = "REJECTED"
+)
+
+// Filing is a document submitted by counsel of record for a case. It moves from DRAFT to SUBMITTED
+// and is then accepted or rejected by a clerk of the organization owning the case. Only accepted
+// filings reach the docket and the documents of the legal record.
+type Filing struct {
+	FilingID     string `json:"filingID"`
+	CaseID       string `json:"caseID"`
+	Title        string `json:"title"`
+	DocumentRef  string `json:"documentRef"`
+	DocumentHash string `json:"documentHash,omitempty" metadata:"documentHash,optional"`
+	Status       string `json:"status"`
+	FiledBy      string `json:"filedBy"`
+	CounselID    string `json:"counselID"`
+	CreatedAt    string `json:"createdAt"`
+	SubmittedAt  string `json:"submittedAt,omitempty" metadata:"submittedAt,optional"`
+	ReviewedBy   string `json:"reviewedBy,omitempty" metadata:"reviewedBy,optional"`
+	ReviewedAt   string `json:"reviewedAt,omitempty" metadata:"reviewedAt,optional"`
+	ReviewReason string `json:"reviewReason,omitempty" metadata:"reviewReason,optional"`
+	DocketNumber int    `json:"docketNumber,omitempty" metadata:"docketNumber,optional"`
+}
+
+// Document is a document attached to a legal record, keyed by case and document reference
+type Document struct {
+	CaseID       string `json:"caseID"`
+	DocumentRef  string `json:"documentRef"`
+	Title        string `json:"title"`
+	DocumentHash string `json:"documentHash,omitempty" metadata:"documentHash,optional"`
+	FilingID     string `json:"filingID"`
+	FiledBy      string `json:"filedBy"`
+	AttachedBy   string `json:"attachedBy"`
+	AttachedAt   string `json:"attachedAt"`
+	DocketNumber int    `json:"docketNumber"`
+}
+
+// CreateFiling creates a draft filing in a case. It can only be called by a lawyer who is active
+// counsel of record.
+func (s *SmartContract) CreateFiling(ctx contractapi.TransactionContextInterface, caseID string, filingJSON string) (*Receipt, error) {
+	legalRecord, counsel, err := getCounselForFiling(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireActiveCounsel(ctx, counsel)
+	if err != nil {
+		return nil, err
+	}
+
+	var input Filing
+	err = json.Unmarshal([]byte(filingJSON), &input)
+	if err != nil {
+		return nil, fmt.Errorf("Failed while unmarshalling filing. %s", err.Error())
+	}
+	err = validateFiling(&input)
+	if err != nil {
+		return nil, err
+	}
+
+	filedBy, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filing := Filing{
+		FilingID:     ctx.GetStub().GetTxID(),
+		CaseID:       legalRecord.CaseID,
+		Title:        input.Title,
+		DocumentRef:  input.DocumentRef,
+		DocumentHash: input.DocumentHash,
+		Status:       FilingDraft,
+		FiledBy:      filedBy,
+		CounselID:    counsel.ID,
+		CreatedAt:    createdAt,
+	}
+	filingAsBytes, err := putFiling(ctx, &filing)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.GetStub().SetEvent("CreateFiling", filingAsBytes)
+
+	return newReceipt(ctx, ReceiptFiling, filing.FilingID, filing.CaseID, filingAsBytes)
+}
+
+// UpdateFiling replaces the title and document of a draft filing
+func (s *SmartContract) UpdateFiling(ctx contractapi.TransactionContextInterface, caseID string, filingID string, filingJSON string) (*Receipt, error) {
+	filing, err := getFilingForFiler(ctx, caseID, filingID)
+	if err != nil {
+		return nil, err
+	}
+
+	var update Filing
+	err = json.Unmarshal([]byte(filingJSON), &update)
+	if err != nil {
+		return nil, fmt.Errorf("Failed while unmarshalling filing. %s", err.Error())
+	}
+	err = validateFiling(&update)
+	if err != nil {
+		return nil, err
+	}
+	filing.Title = update.Title
+	filing.DocumentRef = update.DocumentRef
+	filing.DocumentHash = update.DocumentHash
+
+	filingAsBytes, err := putFiling(ctx, filing)
+	if err != nil {
+		return nil, err
+	}
+	ctx.GetStub().SetEvent("UpdateFiling", filingAsBytes)
+
+	return newReceipt(ctx, ReceiptFiling, filing.FilingID, filing.CaseID, filingAsBytes)
+}
+
+// SubmitFiling submits a draft filing for review by a clerk. The filer must still be active counsel
+// of record and the document must not already be attached to the case.
+func (s *SmartContract) SubmitFiling(ctx contractapi.TransactionContextInterface, caseID string, filingID string) (*Receipt, error) {
+	filing, err := getFilingForFiler(ctx, caseID, filingID)
+	if err != nil {
+		return nil, err
+	}
+	_, counsel, err := getCounselForFiling(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireActiveCounsel(ctx, counsel)
+	if err != nil {
+		return nil, err
+	}
+	err = requireDocumentNotAttached(ctx, caseID, filing.DocumentRef)
+	if err != nil {
+		return nil, err
+	}
+
+	filing.Status = FilingSubmitted
+	filing.SubmittedAt, err = getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filingAsBytes, err := putFiling(ctx, filing)
+	if err != nil {
+		return nil, err
+	}
+	ctx.GetStub().SetEvent("SubmitFiling", filingAsBytes)
+
+	return newReceipt(ctx, ReceiptFiling, filing.FilingID, filing.CaseID, filingAsBytes)
+}
+
+// AcceptFiling accepts a submitted filing. It adds a FILING docket entry and attaches the document to
+// the legal record. reason may be empty.
+func (s *SmartContract) AcceptFiling(ctx contractapi.TransactionContextInterface, caseID string, filingID string, reason string) (*Receipt, error) {
+	filing, legalRecord, err := getFilingForReview(ctx, caseID, filingID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireDocumentNotAttached(ctx, caseID, filing.DocumentRef)
+	if err != nil {
+		return nil, err
+	}
+
+	docketEntry := &DocketEntry{
+		Type:        DocketFiling,
+		Description: fmt.Sprintf("%s, filed by %s", filing.Title, filing.FiledBy),
+		DocumentRef: filing.DocumentRef,
+	}
+	_, err = appendDocketEntry(ctx, caseID, docketEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	err = reviewFiling(ctx, filing, FilingAccepted, reason)
+	if err != nil {
+		return nil, err
+	}
+	filing.DocketNumber = docketEntry.Number
+	filingAsBytes, err := putFiling(ctx, filing)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &Document{
+		CaseID:       caseID,
+		DocumentRef:  filing.DocumentRef,
+		Title:        filing.Title,
+		DocumentHash: filing.DocumentHash,
+		FilingID:     filing.FilingID,
+		FiledBy:      filing.FiledBy,
+		AttachedBy:   filing.ReviewedBy,
+		AttachedAt:   filing.ReviewedAt,
+		DocketNumber: docketEntry.Number,
+	}
+	err = putDocument(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+
+	legalRecord.Documents = append(legalRecord.Documents, filing.DocumentRef)
+	legalRecord.LastUpdated = filing.ReviewedAt
+	legalRecord.LastUpdatedBy = filing.ReviewedBy
+	_, err = putLegalRecord(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.GetStub().SetEvent("AcceptFiling", filingAsBytes)
+
+	return newReceipt(ctx, ReceiptFiling, filing.FilingID, filing.CaseID, filingAsBytes)
+}
+
+// RejectFiling rejects a submitted filing with the reason given to the filer
+func (s *SmartContract) RejectFiling(ctx contractapi.TransactionContextInterface, caseID string, filingID string, reason string) (*Receipt, error) {
+	if len(reason) == 0 {
+		return nil, fmt.Errorf("Please pass a reason for rejecting the filing")
+	}
+
+	filing, _, err := getFilingForReview(ctx, caseID, filingID)
+	if err != nil {
+		return nil, err
+	}
+	err = reviewFiling(ctx, filing, FilingRejected, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	filingAsBytes, err := putFiling(ctx, filing)
+	if err != nil {
+		return nil, err
+	}
+	ctx.GetStub().SetEvent("RejectFiling", filingAsBytes)
+
+	return newReceipt(ctx, ReceiptFiling, filing.FilingID, filing.CaseID, filingAsBytes)
+}
+
+// QueryFilings returns the filings of a case. Filers see their own filings, while clerks, judges and
+// approvers of the organization owning the case, or with access to it, see all filings that have
+// been submitted.
+func (s *SmartContract) QueryFilings(ctx contractapi.TransactionContextInterface, caseID string) ([]*Filing, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := getClientRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	username, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reviewer := role == clerkRole || role == "judge" || role == "approver"
+	if reviewer {
+		err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+		if err != nil && !hasRecordAccess(ctx, legalRecord, username) {
+			return nil, fmt.Errorf("You are not authorized to view the filings of %s", caseID)
+		}
+	} else if !isCounselOfRecord(ctx, legalRecord, username) {
+		return nil, fmt.Errorf("You are not authorized to view the filings of %s", caseID)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(filingObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	filings := []*Filing{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var filing Filing
+		err = json.Unmarshal(queryResponse.Value, &filing)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal filing. %s", err.Error())
+		}
+		if filing.FiledBy == username || (reviewer && filing.Status != FilingDraft) {
+			filings = append(filings, &filing)
+		}
+	}
+
+	return filings, nil
+}
+
+// QueryCaseDocuments returns the documents attached to a case
+func (s *SmartContract) QueryCaseDocuments(ctx contractapi.TransactionContextInterface, caseID string) ([]*Document, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := []*Document{}
+	for _, documentRef := range legalRecord.Documents {
+		document, err := getDocument(ctx, caseID, documentRef)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+func validateFiling(filing *Filing) error {
+	if len(filing.Title) == 0 || len(filing.DocumentRef) == 0 {
+		return fmt.Errorf("Filing title and document reference are required")
+	}
+	if len(filing.DocumentHash) > 0 {
+		documentHash, err := hex.DecodeString(filing.DocumentHash)
+		if err != nil || len(documentHash) != 32 {
+			return fmt.Errorf("Document hash must be a hex encoded SHA-256 digest")
+		}
+		filing.DocumentHash = strings.ToLower(filing.DocumentHash)
+	}
+	return nil
+}
+
+// getCounselForFiling loads a legal record and the counsel of record bound to the calling lawyer
+func getCounselForFiling(ctx contractapi.TransactionContextInterface, caseID string) (*LegalRecord, *Participant, error) {
+	_, err := requireRole(ctx, "lawyer")
+	if err != nil {
+		return nil, nil, err
+	}
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	username, err := getClientName(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, participant := range legalRecord.Participants {
+		if participant.Role == ParticipantCounsel && len(participant.EnrollmentID) > 0 && strings.EqualFold(participant.EnrollmentID, username) {
+			return legalRecord, &legalRecord.Participants[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("Only counsel of record can file in %s", caseID)
+}
+
+// getFilingForFiler loads a draft filing after checking the caller created it
+func getFilingForFiler(ctx contractapi.TransactionContextInterface, caseID string, filingID string) (*Filing, error) {
+	_, err := requireRole(ctx, "lawyer")
+	if err != nil {
+		return nil, err
+	}
+	filing, err := getFiling(ctx, caseID, filingID)
+	if err != nil {
+		return nil, err
+	}
+	username, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if filing.FiledBy != username {
+		return nil, fmt.Errorf("Filing %s was created by another filer", filingID)
+	}
+	if filing.Status != FilingDraft {
+		return nil, fmt.Errorf("Filing %s has already been submitted", filingID)
+	}
+	return filing, nil
+}
+
+// getFilingForReview loads a submitted filing and its legal record after checking the caller is a
+// clerk of the organization owning the case
+func getFilingForReview(ctx contractapi.TransactionContextInterface, caseID string, filingID string) (*Filing, *LegalRecord, error) {
+	_, err := requireRole(ctx, clerkRole)
+	if err != nil {
+		return nil, nil, err
+	}
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filing, err := getFiling(ctx, caseID, filingID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if filing.Status != FilingSubmitted {
+		return nil, nil, fmt.Errorf("Filing %s is %s and cannot be reviewed", filingID, filing.Status)
+	}
+	return filing, legalRecord, nil
+}
+
+func reviewFiling(ctx contractapi.TransactionContextInterface, filing *Filing, status string, reason string) error {
+	reviewedBy, err := getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	reviewedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	filing.Status = status
+	filing.ReviewedBy = reviewedBy
+	filing.ReviewedAt = reviewedAt
+	filing.ReviewReason = reason
+	return nil
+}
+
+func requireDocumentNotAttached(ctx contractapi.TransactionContextInterface, caseID string, documentRef string) error {
+	documentKey, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{caseID, documentRef})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	existing, err := ctx.GetStub().GetState(documentKey)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return fmt.Errorf("Document %s is already attached to %s", documentRef, caseID)
+	}
+	return nil
+}
+
+func getFiling(ctx contractapi.TransactionContextInterface, caseID string, filingID string) (*Filing, error) {
+	filingKey, err := ctx.GetStub().CreateCompositeKey(filingObjectType, []string{caseID, filingID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	filingAsBytes, err := ctx.GetStub().GetState(filingKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if filingAsBytes == nil {
+		return nil, fmt.Errorf("Filing %s does not exist for %s", filingID, caseID)
+	}
+
+	filing := new(Filing)
+	err = json.Unmarshal(filingAsBytes, filing)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal filing. %s", err.Error())
+	}
+	return filing, nil
+}
+
+func putFiling(ctx contractapi.TransactionContextInterface, filing *Filing) ([]byte, error) {
+	err := requireNoLegalHold(ctx, filing.CaseID)
+	if err != nil {
+		return nil, err
+	}
+	filingKey, err := ctx.GetStub().CreateCompositeKey(filingObjectType, []string{filing.CaseID, filing.FilingID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	return putCanonicalState(ctx, filingKey, filing, ContentFiling, filing.CaseID, filing.FilingID)
+}
+
+func getDocument(ctx contractapi.TransactionContextInterface, caseID string, documentRef string) (*Document, error) {
+	documentKey, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{caseID, documentRef})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	documentAsBytes, err := ctx.GetStub().GetState(documentKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if documentAsBytes == nil {
+		return nil, fmt.Errorf("Document %s does not exist for %s", documentRef, caseID)
+	}
+
+	document := new(Document)
+	err = json.Unmarshal(documentAsBytes, document)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal document. %s", err.Error())
+	}
+	return document, nil
+}
+
+func putDocument(ctx contractapi.TransactionContextInterface, document *Document) error {
+	err := requireNoLegalHold(ctx, document.CaseID)
+	if err != nil {
+		return err
+	}
+	documentKey, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{document.CaseID, document.DocumentRef})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	_, err = putCanonicalState(ctx, documentKey, document, ContentDocument, document.CaseID, document.DocumentRef)
+	return err
+}
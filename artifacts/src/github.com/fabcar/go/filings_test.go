@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newFilingEnv returns an environment with lawyer bob registered in NY and counsel of record of the
+// non-public case C1 of CT1
+func newFilingEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":  "C1",
+		"courtID": "CT1",
+		"judges":  []string{},
+		"participants": []map[string]interface{}{
+			{"id": "p1", "name": "Widget Co", "role": "defendant"},
+			{"id": "l1", "name": "Bob Law", "role": "counsel", "barNumber": "NY1", "barJurisdiction": "NY", "represents": []string{"p1"}, "enrollmentID": "bob"},
+		},
+	}))
+	return e
+}
+
+func queryFilings(e *testEnv, caseID string) []*Filing {
+	e.t.Helper()
+	var filings []*Filing
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryFilings", caseID)), &filings)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return filings
+}
+
+func TestCreateFiling(t *testing.T) {
+	e := newFilingEnv(t)
+
+	e.as("Org1MSP", "eve", "lawyer")
+	e.mustFail("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1"}`)
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1"}`)
+
+	e.as("Org1MSP", "bob", "lawyer")
+	e.mustFail("CreateFiling", "C1", `{"title":"Motion"}`)
+	e.mustFail("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1","documentHash":"abc"}`)
+	receipt := e.mustInvoke("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1","documentHash":"`+strings.Repeat("AB", 32)+`"}`)
+	if jsonField(t, receipt, "recordType") != ReceiptFiling || jsonField(t, receipt, "caseID") != "C1" {
+		t.Fatal(receipt)
+	}
+	if hash := jsonField(t, e.mustInvoke("QueryFilingHash", "C1", jsonField(t, receipt, "recordID")), "hash"); hash != jsonField(t, receipt, "recordHash") {
+		t.Fatalf("content hash %s does not match receipt %s", hash, receipt)
+	}
+
+	filings := queryFilings(e, "C1")
+	if len(filings) != 1 || filings[0].Status != FilingDraft || filings[0].CounselID != "l1" || filings[0].DocumentHash != strings.Repeat("ab", 32) {
+		t.Fatalf("unexpected filings %+v", filings)
+	}
+
+	// Suspended counsel can no longer open filings
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("UpdateLawyerStatus", "NY", "NY1", "suspended", "unpaid dues")
+	e.as("Org1MSP", "bob", "lawyer")
+	e.mustFail("CreateFiling", "C1", `{"title":"Answer","documentRef":"doc2"}`)
+}
+
+func TestReviewFiling(t *testing.T) {
+	e := newFilingEnv(t)
+	e.as("Org1MSP", "bob", "lawyer")
+	motionID := jsonField(t, e.mustInvoke("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1"}`), "recordID")
+	answerID := jsonField(t, e.mustInvoke("CreateFiling", "C1", `{"title":"Answer","documentRef":"doc2"}`), "recordID")
+
+	// Drafts can be changed until submitted and are not reviewable
+	e.mustInvoke("UpdateFiling", "C1", motionID, `{"title":"Motion to dismiss","documentRef":"doc1"}`)
+	e.as("Org1MSP", "clerk1", "clerk")
+	e.mustFail("AcceptFiling", "C1", motionID, "")
+	if filings := queryFilings(e, "C1"); len(filings) != 0 {
+		t.Fatalf("clerk sees draft filings %+v", filings)
+	}
+
+	e.as("Org1MSP", "bob", "lawyer")
+	e.mustInvoke("SubmitFiling", "C1", motionID)
+	e.mustInvoke("SubmitFiling", "C1", answerID)
+	e.mustFail("SubmitFiling", "C1", motionID)
+	e.mustFail("UpdateFiling", "C1", motionID, `{"title":"Motion","documentRef":"doc1"}`)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("AcceptFiling", "C1", motionID, "")
+	e.as("Org2MSP", "clerk2", "clerk")
+	e.mustFail("AcceptFiling", "C1", motionID, "")
+
+	e.as("Org1MSP", "clerk1", "clerk")
+	e.mustFail("RejectFiling", "C1", answerID, "")
+	e.mustInvoke("RejectFiling", "C1", answerID, "unsigned")
+	e.mustInvoke("AcceptFiling", "C1", motionID, "in order")
+	e.mustFail("AcceptFiling", "C1", motionID, "")
+	e.mustFail("AcceptFiling", "C1", answerID, "")
+
+	legalRecord := getStoredLegalRecord(e, "C1")
+	if len(legalRecord.Documents) != 1 || legalRecord.Documents[0] != "doc1" {
+		t.Fatalf("unexpected documents %v", legalRecord.Documents)
+	}
+	document, err := getDocumentFromState(e, "C1", "doc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.FilingID != motionID || document.AttachedBy != "clerk1" || document.DocketNumber != 1 {
+		t.Fatalf("unexpected document %+v", document)
+	}
+
+	// An accepted document cannot be filed again
+	e.as("Org1MSP", "bob", "lawyer")
+	againID := jsonField(t, e.mustInvoke("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1"}`), "recordID")
+	e.mustFail("SubmitFiling", "C1", againID)
+}
+
+func TestQueryFilings(t *testing.T) {
+	e := newFilingEnv(t)
+	e.as("Org1MSP", "bob", "lawyer")
+	filingID := jsonField(t, e.mustInvoke("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1"}`), "recordID")
+	e.mustInvoke("SubmitFiling", "C1", filingID)
+
+	for _, role := range []string{"clerk", "judge", "approver"} {
+		e.as("Org1MSP", "reviewer1", role)
+		if filings := queryFilings(e, "C1"); len(filings) != 1 || filings[0].FilingID != filingID {
+			t.Fatalf("%s of the owning organization sees %+v", role, filings)
+		}
+		// Reviewers of another organization without access to the case see nothing
+		e.as("Org2MSP", "reviewer2", role)
+		e.mustFail("QueryFilings", "C1")
+	}
+
+	e.as("Org1MSP", "eve", "lawyer")
+	e.mustFail("QueryFilings", "C1")
+	e.as("Org1MSP", "eve", "client")
+	e.mustFail("QueryFilings", "C1")
+}
+
+func getDocumentFromState(e *testEnv, caseID string, documentRef string) (*Document, error) {
+	documentKey, err := e.stub.CreateCompositeKey(documentObjectType, []string{caseID, documentRef})
+	if err != nil {
+		return nil, err
+	}
+	document := new(Document)
+	err = json.Unmarshal(e.stub.State[documentKey], document)
+	return document, err
+}
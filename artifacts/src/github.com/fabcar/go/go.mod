@@ -3,10 +3,13 @@ module github.com/fabcar_contract_api/go
 go 1.13
 
 require (
+	github.com/golang/protobuf v1.3.2
 	github.com/hyperledger/fabric v2.1.1+incompatible
 	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200128192331-2d899240a7ed
 	github.com/hyperledger/fabric-contract-api-go v1.0.0
+	github.com/hyperledger/fabric-protos-go v0.0.0-20200124220212-e9cfc186ba7b
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/sykesm/zap-logfmt v0.0.4 // indirect
 	go.uber.org/zap v1.16.0 // indirect
+	golang.org/x/text v0.3.2
 )
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	hearingObjectType           = "hearing"
+	judgeCalendarObjectType     = "judgeCalendar"
+	courtroomCalendarObjectType = "courtroomCalendar"
+
+	HearingScheduled = "SCHEDULED"
+	HearingHeld      = "HELD"
+	HearingCancelled = "CANCELLED"
+)
+
+// Hearing is a scheduled session of a case. Every scheduled hearing is also indexed in the
+// calendar of its judge and courtroom, keyed by start time, so double bookings can be detected.
+type Hearing struct {
+	HearingID   string `json:"hearingID"`
+	CaseID      string `json:"caseID"`
+	CourtID     string `json:"courtID"`
+	Courtroom   string `json:"courtroom"`
+	JudgeID     string `json:"judgeID"`
+	Type        string `json:"type"`
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	Status      string `json:"status"`
+	Outcome     string `json:"outcome,omitempty" metadata:"outcome,optional"`
+	ScheduledBy string `json:"scheduledBy"`
+	ScheduledAt string `json:"scheduledAt"`
+}
+
+// ScheduleHearing schedules a hearing for a case before one of its assigned judges. It fails if the
+// judge or the courtroom already has a hearing in an overlapping slot.
+func (s *SmartContract) ScheduleHearing(ctx contractapi.TransactionContextInterface, caseID string, hearingData string) (string, error) {
+	legalRecord, err := getLegalRecordForJudgeChange(ctx, caseID)
+	if err != nil {
+		return "", err
+	}
+	if len(legalRecord.CourtID) == 0 {
+		return "", fmt.Errorf("%s does not reference a registered court", caseID)
+	}
+
+	var hearing Hearing
+	err = json.Unmarshal([]byte(hearingData), &hearing)
+	if err != nil {
+		return "", fmt.Errorf("Failed while unmarshalling hearing. %s", err.Error())
+	}
+	if len(hearing.Courtroom) == 0 || len(hearing.Type) == 0 {
+		return "", fmt.Errorf("Hearing courtroom and type are required")
+	}
+	if !isJudgeAssigned(legalRecord, hearing.JudgeID) {
+		return "", fmt.Errorf("Judge %s is not assigned to %s", hearing.JudgeID, caseID)
+	}
+
+	start, end, err := parseTimeSlot(hearing.Start, hearing.End)
+	if err != nil {
+		return "", err
+	}
+
+	scheduledBy, err := getClientName(ctx)
+	if err != nil {
+		return "", err
+	}
+	scheduledAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	hearing.HearingID = ctx.GetStub().GetTxID()
+	hearing.CaseID = caseID
+	hearing.CourtID = legalRecord.CourtID
+	hearing.Type = strings.ToUpper(hearing.Type)
+	hearing.Start = start.Format(time.RFC3339)
+	hearing.End = end.Format(time.RFC3339)
+	hearing.Status = HearingScheduled
+	hearing.Outcome = ""
+	hearing.ScheduledBy = scheduledBy
+	hearing.ScheduledAt = scheduledAt
+
+	judgeHearings, err := getCalendar(ctx, judgeCalendarObjectType, []string{hearing.JudgeID}, start, end)
+	if err != nil {
+		return "", err
+	}
+	if len(judgeHearings) > 0 {
+		return "", fmt.Errorf("Judge %s already has hearing %s from %s to %s", hearing.JudgeID, judgeHearings[0].HearingID, judgeHearings[0].Start, judgeHearings[0].End)
+	}
+	courtroomHearings, err := getCalendar(ctx, courtroomCalendarObjectType, []string{hearing.CourtID, hearing.Courtroom}, start, end)
+	if err != nil {
+		return "", err
+	}
+	if len(courtroomHearings) > 0 {
+		return "", fmt.Errorf("Courtroom %s already has hearing %s from %s to %s", hearing.Courtroom, courtroomHearings[0].HearingID, courtroomHearings[0].Start, courtroomHearings[0].End)
+	}
+
+	hearingAsBytes, err := putHearing(ctx, &hearing)
+	if err != nil {
+		return "", err
+	}
+	err = putCalendarEntries(ctx, &hearing)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.GetStub().SetEvent("ScheduleHearing", hearingAsBytes)
+
+	return hearing.HearingID, nil
+}
+
+// RecordHearingOutcome marks a hearing as held and records its outcome. It can be called by the
+// judge of the hearing (through the identity bound to the judge) or by an approver of the owning court.
+func (s *SmartContract) RecordHearingOutcome(ctx contractapi.TransactionContextInterface, caseID string, hearingID string, outcome string) error {
+	if len(outcome) == 0 {
+		return fmt.Errorf("Please pass the hearing outcome")
+	}
+
+	hearing, err := getHearingForChange(ctx, caseID, hearingID)
+	if err != nil {
+		return err
+	}
+	hearing.Status = HearingHeld
+	hearing.Outcome = outcome
+
+	hearingAsBytes, err := putHearing(ctx, hearing)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("RecordHearingOutcome", hearingAsBytes)
+}
+
+// CancelHearing cancels a scheduled hearing and frees its slot in the judge's and courtroom's calendar
+func (s *SmartContract) CancelHearing(ctx contractapi.TransactionContextInterface, caseID string, hearingID string, reason string) error {
+	if len(reason) == 0 {
+		return fmt.Errorf("Please pass a reason for the cancellation")
+	}
+
+	hearing, err := getHearingForChange(ctx, caseID, hearingID)
+	if err != nil {
+		return err
+	}
+	hearing.Status = HearingCancelled
+	hearing.Outcome = reason
+
+	err = deleteCalendarEntries(ctx, hearing)
+	if err != nil {
+		return err
+	}
+	hearingAsBytes, err := putHearing(ctx, hearing)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("CancelHearing", hearingAsBytes)
+}
+
+// QueryHearings returns all hearings of a case, including held and cancelled ones, to callers who
+// may read its docket
+func (s *SmartContract) QueryHearings(ctx contractapi.TransactionContextInterface, caseID string) ([]*Hearing, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(hearingObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var hearings []*Hearing
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var hearing Hearing
+		err = json.Unmarshal(queryResponse.Value, &hearing)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal hearing. %s", err.Error())
+		}
+		hearings = append(hearings, &hearing)
+	}
+
+	return hearings, nil
+}
+
+// GetJudgeCalendar returns the hearings of a judge that overlap the range from..to (RFC 3339)
+func (s *SmartContract) GetJudgeCalendar(ctx contractapi.TransactionContextInterface, judgeID string, from string, to string) ([]*Hearing, error) {
+	if len(judgeID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct judge id")
+	}
+	start, end, err := parseTimeSlot(from, to)
+	if err != nil {
+		return nil, err
+	}
+	hearings, err := getCalendar(ctx, judgeCalendarObjectType, []string{judgeID}, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return redactCalendar(ctx, hearings)
+}
+
+// GetCourtroomCalendar returns the hearings in a courtroom that overlap the range from..to (RFC 3339)
+func (s *SmartContract) GetCourtroomCalendar(ctx contractapi.TransactionContextInterface, courtID string, courtroom string, from string, to string) ([]*Hearing, error) {
+	if len(courtID) == 0 || len(courtroom) == 0 {
+		return nil, fmt.Errorf("Please pass the correct court id and courtroom")
+	}
+	start, end, err := parseTimeSlot(from, to)
+	if err != nil {
+		return nil, err
+	}
+	hearings, err := getCalendar(ctx, courtroomCalendarObjectType, []string{courtID, courtroom}, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return redactCalendar(ctx, hearings)
+}
+
+// redactCalendar clears the type and outcome of the hearings of cases the caller only sees the summary
+// of, so calendars show booked slots without revealing what happens in them
+func redactCalendar(ctx contractapi.TransactionContextInterface, hearings []*Hearing) ([]*Hearing, error) {
+	recordViews := make(map[string]string)
+	for _, hearing := range hearings {
+		recordView, ok := recordViews[hearing.CaseID]
+		if !ok {
+			legalRecord, err := getLegalRecord(ctx, hearing.CaseID)
+			if err != nil {
+				return nil, err
+			}
+			recordView, err = getRecordView(ctx, legalRecord)
+			if err != nil {
+				return nil, err
+			}
+			recordViews[hearing.CaseID] = recordView
+		}
+		if recordView == RecordViewSummary {
+			hearing.Type = ""
+			hearing.Outcome = ""
+		}
+	}
+	return hearings, nil
+}
+
+// parseTimeSlot parses an RFC 3339 start and end and checks that the slot is not empty
+func parseTimeSlot(from string, to string) (time.Time, time.Time, error) {
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Invalid start time %s. %s", from, err.Error())
+	}
+	end, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Invalid end time %s. %s", to, err.Error())
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("End time %s must be after start time %s", to, from)
+	}
+	return start.UTC(), end.UTC(), nil
+}
+
+// getCalendar returns the hearings in a judge or courtroom calendar that overlap start..end.
+// Calendar entries are keyed by start time in UTC, so the scan stops at the first hearing
+// starting at or after end.
+func getCalendar(ctx contractapi.TransactionContextInterface, objectType string, attributes []string, start time.Time, end time.Time) ([]*Hearing, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	endKey := end.Format(time.RFC3339)
+	var hearings []*Hearing
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to split composite key. %s", err.Error())
+		}
+		hearingStart, hearingID := keyParts[len(keyParts)-2], keyParts[len(keyParts)-1]
+		if hearingStart >= endKey {
+			break
+		}
+
+		hearing, err := getHearing(ctx, string(queryResponse.Value), hearingID)
+		if err != nil {
+			return nil, err
+		}
+		hearingEnd, err := time.Parse(time.RFC3339, hearing.End)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid end time on hearing %s. %s", hearingID, err.Error())
+		}
+		if hearingEnd.After(start) {
+			hearings = append(hearings, hearing)
+		}
+	}
+
+	return hearings, nil
+}
+
+// calendarKeys returns the judge and courtroom calendar keys of a hearing
+func calendarKeys(ctx contractapi.TransactionContextInterface, hearing *Hearing) ([]string, error) {
+	judgeKey, err := ctx.GetStub().CreateCompositeKey(judgeCalendarObjectType, []string{hearing.JudgeID, hearing.Start, hearing.HearingID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	courtroomKey, err := ctx.GetStub().CreateCompositeKey(courtroomCalendarObjectType, []string{hearing.CourtID, hearing.Courtroom, hearing.Start, hearing.HearingID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	return []string{judgeKey, courtroomKey}, nil
+}
+
+// putCalendarEntries indexes a hearing in its judge's and courtroom's calendar. The entries only
+// hold the case ID; the hearing itself is read from its own key.
+func putCalendarEntries(ctx contractapi.TransactionContextInterface, hearing *Hearing) error {
+	keys, err := calendarKeys(ctx, hearing)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = ctx.GetStub().PutState(key, []byte(hearing.CaseID))
+		if err != nil {
+			return fmt.Errorf("Failed to put calendar entry. %s", err.Error())
+		}
+	}
+	return nil
+}
+
+func deleteCalendarEntries(ctx contractapi.TransactionContextInterface, hearing *Hearing) error {
+	keys, err := calendarKeys(ctx, hearing)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = ctx.GetStub().DelState(key)
+		if err != nil {
+			return fmt.Errorf("Failed to delete calendar entry. %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// getHearingForChange loads a scheduled hearing after checking the caller is its judge or an
+// approver of the owning court
+func getHearingForChange(ctx contractapi.TransactionContextInterface, caseID string, hearingID string) (*Hearing, error) {
+	role, err := requireRole(ctx, "approver", "judge")
+	if err != nil {
+		return nil, err
+	}
+
+	hearing, err := getHearing(ctx, caseID, hearingID)
+	if err != nil {
+		return nil, err
+	}
+	if hearing.Status != HearingScheduled {
+		return nil, fmt.Errorf("Hearing %s is already %s", hearingID, strings.ToLower(hearing.Status))
+	}
+
+	if role == "judge" {
+		_, err = getJudgeForClient(ctx, hearing.JudgeID)
+	} else {
+		var legalRecord *LegalRecord
+		legalRecord, err = getLegalRecord(ctx, caseID)
+		if err == nil && len(legalRecord.OwnerMSP) > 0 {
+			err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hearing, nil
+}
+
+func getHearing(ctx contractapi.TransactionContextInterface, caseID string, hearingID string) (*Hearing, error) {
+	hearingKey, err := ctx.GetStub().CreateCompositeKey(hearingObjectType, []string{caseID, hearingID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	hearingAsBytes, err := ctx.GetStub().GetState(hearingKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if hearingAsBytes == nil {
+		return nil, fmt.Errorf("Hearing %s does not exist for %s", hearingID, caseID)
+	}
+
+	hearing := new(Hearing)
+	err = json.Unmarshal(hearingAsBytes, hearing)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal hearing. %s", err.Error())
+	}
+	return hearing, nil
+}
+
+func putHearing(ctx contractapi.TransactionContextInterface, hearing *Hearing) ([]byte, error) {
+	hearingKey, err := ctx.GetStub().CreateCompositeKey(hearingObjectType, []string{hearing.CaseID, hearing.HearingID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	hearingAsBytes, err := json.Marshal(hearing)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal hearing. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(hearingKey, hearingAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put hearing. %s", err.Error())
+	}
+	return hearingAsBytes, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newHearingEnv returns an environment with the non-public case C1 and the public case P1 of CT1,
+// both assigned to judges j and k, and alice granted access to C1
+func newHearingEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT1","active":true,"enrollmentID":"k"}`)
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{"j", "k"}, "usersWithAccess": []string{"alice"}}))
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "P1", "courtID": "CT1", "judges": []string{"j", "k"}, "confidentiality": "PUBLIC"}))
+	return e
+}
+
+func hearingData(judgeID string, courtroom string, start string, end string) string {
+	return `{"judgeID":"` + judgeID + `","courtroom":"` + courtroom + `","type":"motion","start":"2026-03-02T` + start + `:00Z","end":"2026-03-02T` + end + `:00Z"}`
+}
+
+func decodeHearings(e *testEnv, payload string) []*Hearing {
+	e.t.Helper()
+	var hearings []*Hearing
+	if len(payload) == 0 {
+		return hearings
+	}
+	err := json.Unmarshal([]byte(payload), &hearings)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return hearings
+}
+
+func TestScheduleHearing(t *testing.T) {
+	e := newHearingEnv(t)
+	e.mustInvoke("ScheduleHearing", "C1", hearingData("j", "R1", "09:00", "10:00"))
+
+	// Overlapping slots of the same judge or courtroom are rejected, adjacent ones are not
+	e.mustFail("ScheduleHearing", "P1", hearingData("j", "R2", "09:30", "10:30"))
+	e.mustFail("ScheduleHearing", "P1", hearingData("k", "R1", "08:30", "09:01"))
+	e.mustInvoke("ScheduleHearing", "P1", hearingData("k", "R1", "10:00", "11:00"))
+	e.mustInvoke("ScheduleHearing", "P1", hearingData("j", "R2", "08:00", "09:00"))
+
+	e.mustFail("ScheduleHearing", "C1", hearingData("m", "R3", "12:00", "13:00"))
+	e.mustFail("ScheduleHearing", "C1", hearingData("j", "R3", "13:00", "12:00"))
+	e.mustFail("ScheduleHearing", "C1", `{"judgeID":"j","start":"2026-03-02T12:00:00Z","end":"2026-03-02T13:00:00Z"}`)
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("ScheduleHearing", "C1", hearingData("j", "R3", "12:00", "13:00"))
+}
+
+func TestHearingOutcome(t *testing.T) {
+	e := newHearingEnv(t)
+	hearingID := e.mustInvoke("ScheduleHearing", "C1", hearingData("j", "R1", "09:00", "10:00"))
+
+	e.as("Org1MSP", "k", "judge")
+	e.mustFail("RecordHearingOutcome", "C1", hearingID, "granted")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("RecordHearingOutcome", "C1", hearingID, "granted")
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("RecordHearingOutcome", "C1", hearingID, "")
+	e.mustInvoke("RecordHearingOutcome", "C1", hearingID, "granted")
+
+	// Cancelling frees the slot
+	e.as("Org1MSP", "admin1", "approver")
+	otherID := e.mustInvoke("ScheduleHearing", "C1", hearingData("j", "R1", "11:00", "12:00"))
+	e.mustInvoke("CancelHearing", "C1", otherID, "settled")
+	e.mustInvoke("ScheduleHearing", "P1", hearingData("j", "R1", "11:00", "12:00"))
+}
+
+func TestQueryHearingsAccess(t *testing.T) {
+	e := newHearingEnv(t)
+	e.mustInvoke("ScheduleHearing", "C1", hearingData("j", "R1", "09:00", "10:00"))
+	e.mustInvoke("ScheduleHearing", "P1", hearingData("j", "R1", "10:00", "11:00"))
+
+	for _, reader := range [][]string{{"Org1MSP", "admin1", "approver"}, {"Org1MSP", "k", "judge"}, {"Org2MSP", "alice", "client"}} {
+		e.as(reader[0], reader[1], reader[2])
+		if hearings := decodeHearings(e, e.mustInvoke("QueryHearings", "C1")); len(hearings) != 1 || hearings[0].Type != "MOTION" {
+			t.Fatalf("%s sees %+v", reader[1], hearings)
+		}
+	}
+
+	// Callers who only see the summary of the case get no hearings and a redacted calendar
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("QueryHearings", "C1")
+	e.mustFail("QueryHearings", "C404")
+	if hearings := decodeHearings(e, e.mustInvoke("QueryHearings", "P1")); len(hearings) != 1 {
+		t.Fatalf("public hearings %+v", hearings)
+	}
+	calendar := decodeHearings(e, e.mustInvoke("GetCourtroomCalendar", "CT1", "R1", "2026-03-02T00:00:00Z", "2026-03-03T00:00:00Z"))
+	if len(calendar) != 2 || calendar[0].CaseID != "C1" || calendar[0].Type != "" || calendar[1].Type != "MOTION" {
+		t.Fatalf("unexpected calendar %+v", calendar)
+	}
+
+	e.as("Org1MSP", "j", "judge")
+	calendar = decodeHearings(e, e.mustInvoke("GetJudgeCalendar", "j", "2026-03-02T09:30:00Z", "2026-03-02T10:00:00Z"))
+	if len(calendar) != 1 || calendar[0].Type != "MOTION" {
+		t.Fatalf("unexpected calendar %+v", calendar)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/attrmgr"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// testSalt is the private data salt passed in the transient map unless a test sets its own
+const testSalt = "0123456789abcdef"
+
+// testEnv invokes the chaincode on a mock stub as a chosen identity, one transaction per call
+type testEnv struct {
+	t         *testing.T
+	stub      *shimtest.MockStub
+	cc        *contractapi.ContractChaincode
+	transient map[string][]byte
+	txCount   int
+}
+
+// transientStub passes the arguments and transient map of a call to the chaincode, as the mock stub
+// does not implement GetTransient
+type transientStub struct {
+	*shimtest.MockStub
+	args      [][]byte
+	transient map[string][]byte
+}
+
+func (s *transientStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *transientStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *transientStub) GetStringArgs() []string {
+	args := make([]string, 0, len(s.args))
+	for _, arg := range s.args {
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+func (s *transientStub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	return args[0], args[1:]
+}
+
+// newTestEnv returns an environment with court CT1 of Org1MSP serving zip codes 10001 and 10002, its
+// active judge j, and court CT2 of Org2MSP serving zip code 20001
+func newTestEnv(t *testing.T) *testEnv {
+	cc, err := contractapi.NewChaincode(new(SmartContract))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &testEnv{t: t, stub: shimtest.NewMockStub("fabcar", cc), cc: cc}
+	e.setPrivateDetails(map[string]string{})
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateCourt", `{"id":"CT1","name":"District 1","type":"district","category":"civil","zipCodes":["10001","10002"]}`)
+	e.mustInvoke("CreateJudge", `{"id":"j","name":"Judy","courtID":"CT1","active":true,"enrollmentID":"j"}`)
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("CreateCourt", `{"id":"CT2","name":"District 2","type":"district","category":"civil","zipCodes":["20001"]}`)
+	return e
+}
+
+// as makes the following calls with an identity of mspID named name, holding the role attribute
+func (e *testEnv) as(mspID string, name string, role string) {
+	e.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	err = attrmgr.New().AddAttributesToCert(&attrmgr.Attributes{Attrs: map[string]string{"role": role}}, template)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	template.ExtraExtensions = template.Extensions
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}),
+	})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	e.stub.Creator = creator
+}
+
+// setPrivateDetails sets the legal record private details passed in the transient map of the
+// following calls, adding the default salt when none is given
+func (e *testEnv) setPrivateDetails(details map[string]string) {
+	if _, ok := details["salt"]; !ok {
+		details["salt"] = testSalt
+	}
+	e.transient = map[string][]byte{legalRecordPrivateTransientKey: []byte(toJSON(e.t, details))}
+}
+
+// invoke submits fn as a transaction with IDs tx0001, tx0002, ... and returns its payload
+func (e *testEnv) invoke(fn string, args ...string) (string, error) {
+	e.txCount++
+	txID := fmt.Sprintf("tx%04d", e.txCount)
+
+	invokeArgs := [][]byte{[]byte(fn)}
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+
+	e.stub.MockTransactionStart(txID)
+	response := e.cc.Invoke(&transientStub{MockStub: e.stub, args: invokeArgs, transient: e.transient})
+	e.stub.MockTransactionEnd(txID)
+	if response.Status != 200 {
+		return "", fmt.Errorf("%s", response.Message)
+	}
+	return string(response.Payload), nil
+}
+
+func (e *testEnv) mustInvoke(fn string, args ...string) string {
+	e.t.Helper()
+	payload, err := e.invoke(fn, args...)
+	if err != nil {
+		e.t.Fatalf("%s failed: %s", fn, err.Error())
+	}
+	return payload
+}
+
+// mustFail invokes fn expecting it to fail and returns the error message
+func (e *testEnv) mustFail(fn string, args ...string) string {
+	e.t.Helper()
+	payload, err := e.invoke(fn, args...)
+	if err == nil {
+		e.t.Fatalf("%s succeeded with %s, expected an error", fn, payload)
+	}
+	return err.Error()
+}
+
+func toJSON(t *testing.T, value interface{}) string {
+	t.Helper()
+	valueAsBytes, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(valueAsBytes)
+}
+
+// jsonField returns a field of a JSON object formatted as a string
+func jsonField(t *testing.T, objectJSON string, field string) string {
+	t.Helper()
+	var object map[string]interface{}
+	err := json.Unmarshal([]byte(objectJSON), &object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprint(object[field])
+}
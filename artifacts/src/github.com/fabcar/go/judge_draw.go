@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const judgeDrawObjectType = "judgeDraw"
+
+// JudgeDrawCandidate is an eligible judge in a random assignment draw. Less loaded judges get a
+// larger weight so the draw also balances work across the court.
+type JudgeDrawCandidate struct {
+	JudgeID  string `json:"judgeID"`
+	CaseLoad int    `json:"caseLoad"`
+	Weight   int64  `json:"weight"`
+}
+
+// JudgeDraw records everything needed to re-verify a random judge assignment. The seed is
+// SHA-256(txID || recordHash), so every endorser computes the same draw and anyone can repeat it.
+type JudgeDraw struct {
+	CaseID          string               `json:"caseID"`
+	TxID            string               `json:"txID"`
+	RecordHash      string               `json:"recordHash"`
+	Seed            string               `json:"seed"`
+	Candidates      []JudgeDrawCandidate `json:"candidates"`
+	TotalWeight     int64                `json:"totalWeight"`
+	Draw            int64                `json:"draw"`
+	SelectedJudgeID string               `json:"selectedJudgeID"`
+	DrawnAt         string               `json:"drawnAt"`
+	DrawnBy         string               `json:"drawnBy"`
+}
+
+// AutoAssignJudge randomly assigns one of the eligible active judges of the case's court
+func (s *SmartContract) AutoAssignJudge(ctx contractapi.TransactionContextInterface, caseID string) (*JudgeDraw, error) {
+	legalRecord, err := getLegalRecordForJudgeChange(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if len(legalRecord.CourtID) == 0 {
+		return nil, fmt.Errorf("%s does not reference a registered court", caseID)
+	}
+
+	legalRecordAsBytes, err := ctx.GetStub().GetState(caseID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	recordHash := sha256.Sum256(legalRecordAsBytes)
+
+	judges, err := getJudgesByCourt(ctx, legalRecord.CourtID)
+	if err != nil {
+		return nil, err
+	}
+	var eligible []*Judge
+	for _, judge := range judges {
+		if judge.Active && !isJudgeAssigned(legalRecord, judge.ID) {
+			if _, err := checkJudgeConflicts(legalRecord, judge); err == nil {
+				eligible = append(eligible, judge)
+			}
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, fmt.Errorf("No eligible judge available in court %s", legalRecord.CourtID)
+	}
+
+	drawnAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	drawnBy, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	draw := &JudgeDraw{
+		CaseID:     caseID,
+		TxID:       ctx.GetStub().GetTxID(),
+		RecordHash: hex.EncodeToString(recordHash[:]),
+		Candidates: judgeDrawCandidates(eligible),
+		DrawnAt:    drawnAt,
+		DrawnBy:    drawnBy,
+	}
+	err = runJudgeDraw(draw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = assignJudge(ctx, legalRecord, draw.SelectedJudgeID, "random assignment "+draw.TxID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = putLegalRecord(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	drawKey, err := ctx.GetStub().CreateCompositeKey(judgeDrawObjectType, []string{caseID, draw.TxID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	drawAsBytes, err := json.Marshal(draw)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal judge draw. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(drawKey, drawAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put judge draw. %s", err.Error())
+	}
+
+	ctx.GetStub().SetEvent("AutoAssignJudge", drawAsBytes)
+
+	return draw, nil
+}
+
+// GetJudgeDraws returns the random assignment draws made for a case
+func (s *SmartContract) GetJudgeDraws(ctx contractapi.TransactionContextInterface, caseID string) ([]*JudgeDraw, error) {
+	if len(caseID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct case id")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(judgeDrawObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var draws []*JudgeDraw
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var draw JudgeDraw
+		err = json.Unmarshal(queryResponse.Value, &draw)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal judge draw. %s", err.Error())
+		}
+		draws = append(draws, &draw)
+	}
+
+	return draws, nil
+}
+
+// VerifyJudgeDraw recomputes a stored draw from its transaction ID, record hash and candidates
+func (s *SmartContract) VerifyJudgeDraw(ctx contractapi.TransactionContextInterface, caseID string, txID string) (bool, error) {
+	drawKey, err := ctx.GetStub().CreateCompositeKey(judgeDrawObjectType, []string{caseID, txID})
+	if err != nil {
+		return false, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	drawAsBytes, err := ctx.GetStub().GetState(drawKey)
+	if err != nil {
+		return false, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if drawAsBytes == nil {
+		return false, fmt.Errorf("Judge draw %s does not exist for %s", txID, caseID)
+	}
+
+	var stored JudgeDraw
+	err = json.Unmarshal(drawAsBytes, &stored)
+	if err != nil {
+		return false, fmt.Errorf("Failed to unmarshal judge draw. %s", err.Error())
+	}
+
+	replay := stored
+	err = runJudgeDraw(&replay)
+	if err != nil {
+		return false, err
+	}
+
+	return replay.Seed == stored.Seed && replay.Draw == stored.Draw && replay.SelectedJudgeID == stored.SelectedJudgeID, nil
+}
+
+// judgeDrawCandidates orders the eligible judges by ID and weights them by case load
+func judgeDrawCandidates(judges []*Judge) []JudgeDrawCandidate {
+	sort.Slice(judges, func(i, j int) bool { return judges[i].ID < judges[j].ID })
+
+	maxLoad := 0
+	for _, judge := range judges {
+		if judge.CaseLoad > maxLoad {
+			maxLoad = judge.CaseLoad
+		}
+	}
+
+	var candidates []JudgeDrawCandidate
+	for _, judge := range judges {
+		candidates = append(candidates, JudgeDrawCandidate{
+			JudgeID:  judge.ID,
+			CaseLoad: judge.CaseLoad,
+			Weight:   int64(maxLoad - judge.CaseLoad + 1),
+		})
+	}
+	return candidates
+}
+
+// runJudgeDraw derives the seed and picks the candidate whose cumulative weight covers seed mod total
+func runJudgeDraw(draw *JudgeDraw) error {
+	seed := sha256.Sum256([]byte(draw.TxID + draw.RecordHash))
+	draw.Seed = hex.EncodeToString(seed[:])
+
+	draw.TotalWeight = 0
+	for _, candidate := range draw.Candidates {
+		draw.TotalWeight += candidate.Weight
+	}
+	if draw.TotalWeight <= 0 {
+		return fmt.Errorf("Judge draw for %s has no candidates", draw.CaseID)
+	}
+
+	draw.Draw = new(big.Int).Mod(new(big.Int).SetBytes(seed[:]), big.NewInt(draw.TotalWeight)).Int64()
+
+	cumulative := int64(0)
+	for _, candidate := range draw.Candidates {
+		cumulative += candidate.Weight
+		if draw.Draw < cumulative {
+			draw.SelectedJudgeID = candidate.JudgeID
+			break
+		}
+	}
+	return nil
+}
+
+func isJudgeAssigned(legalRecord *LegalRecord, judgeID string) bool {
+	for _, assigned := range legalRecord.Judges {
+		if assigned == judgeID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJudgeDrawCandidates(t *testing.T) {
+	candidates := judgeDrawCandidates([]*Judge{{ID: "m", CaseLoad: 3}, {ID: "a", CaseLoad: 0}, {ID: "k", CaseLoad: 1}})
+	if len(candidates) != 3 {
+		t.Fatalf("unexpected candidates %+v", candidates)
+	}
+	// Candidates are ordered by ID and the least loaded judge weighs most
+	for i, want := range []JudgeDrawCandidate{{"a", 0, 4}, {"k", 1, 3}, {"m", 3, 1}} {
+		if candidates[i] != want {
+			t.Fatalf("candidate %d is %+v, want %+v", i, candidates[i], want)
+		}
+	}
+}
+
+func TestRunJudgeDraw(t *testing.T) {
+	draw := &JudgeDraw{CaseID: "C1", TxID: "tx0001", RecordHash: "abc", Candidates: []JudgeDrawCandidate{{"a", 0, 4}, {"k", 1, 3}, {"m", 3, 1}}}
+	err := runJudgeDraw(draw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draw.TotalWeight != 8 || draw.Draw < 0 || draw.Draw >= 8 || len(draw.SelectedJudgeID) == 0 {
+		t.Fatalf("unexpected draw %+v", draw)
+	}
+
+	// The draw only depends on the transaction, record hash and candidates
+	replay := &JudgeDraw{CaseID: "C1", TxID: "tx0001", RecordHash: "abc", Candidates: draw.Candidates}
+	err = runJudgeDraw(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Seed != draw.Seed || replay.Draw != draw.Draw || replay.SelectedJudgeID != draw.SelectedJudgeID {
+		t.Fatalf("replay %+v differs from %+v", replay, draw)
+	}
+
+	selected := make(map[string]int)
+	for _, txID := range []string{"tx0001", "tx0002", "tx0003", "tx0004", "tx0005", "tx0006", "tx0007", "tx0008", "tx0009", "tx0010", "tx0011", "tx0012"} {
+		other := &JudgeDraw{CaseID: "C1", TxID: txID, RecordHash: "abc", Candidates: draw.Candidates}
+		err = runJudgeDraw(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		selected[other.SelectedJudgeID]++
+	}
+	if len(selected) < 2 {
+		t.Fatalf("draws always select the same judge %v", selected)
+	}
+
+	err = runJudgeDraw(&JudgeDraw{CaseID: "C1", TxID: "tx0001"})
+	if err == nil {
+		t.Fatal("draw without candidates succeeded")
+	}
+}
+
+func TestAutoAssignJudge(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"i","name":"Ian","courtID":"CT1","active":false}`)
+	e.mustInvoke("CreateJudge", toJSON(t, map[string]interface{}{
+		"id":            "k",
+		"name":          "Kim",
+		"courtID":       "CT1",
+		"active":        true,
+		"relationships": []map[string]interface{}{{"name": "Acme", "kind": "party", "description": "shareholder", "disqualifying": true}},
+	}))
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"o","name":"Otto","courtID":"CT2","active":true}`)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":       "C1",
+		"courtID":      "CT1",
+		"judges":       []string{},
+		"participants": []map[string]interface{}{{"id": "p1", "name": "Acme", "role": "defendant"}},
+	}))
+
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("AutoAssignJudge", "C1")
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("AutoAssignJudge", "C1")
+
+	// Only j is active in CT1 and free of disqualifying conflicts
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("AutoAssignJudge", "C9")
+	draw := new(JudgeDraw)
+	err := json.Unmarshal([]byte(e.mustInvoke("AutoAssignJudge", "C1")), draw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draw.SelectedJudgeID != "j" || len(draw.Candidates) != 1 || draw.DrawnBy != "admin1" {
+		t.Fatalf("unexpected draw %+v", draw)
+	}
+	if legalRecord := getStoredLegalRecord(e, "C1"); len(legalRecord.Judges) != 1 || legalRecord.Judges[0] != "j" {
+		t.Fatalf("unexpected judges %v", legalRecord.Judges)
+	}
+	e.mustFail("AutoAssignJudge", "C1")
+
+	var draws []*JudgeDraw
+	err = json.Unmarshal([]byte(e.mustInvoke("GetJudgeDraws", "C1")), &draws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(draws) != 1 || draws[0].TxID != draw.TxID || draws[0].Seed != draw.Seed {
+		t.Fatalf("unexpected draws %+v", draws)
+	}
+	if verified := e.mustInvoke("VerifyJudgeDraw", "C1", draw.TxID); verified != "true" {
+		t.Fatal("stored draw does not verify")
+	}
+	e.mustFail("VerifyJudgeDraw", "C1", "tx9999")
+
+	// A draw whose selection was changed afterwards no longer verifies
+	draw.SelectedJudgeID = "k"
+	drawKey, err := e.stub.CreateCompositeKey(judgeDrawObjectType, []string{"C1", draw.TxID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.stub.State[drawKey] = []byte(toJSON(t, draw))
+	if verified := e.mustInvoke("VerifyJudgeDraw", "C1", draw.TxID); verified != "false" {
+		t.Fatal("tampered draw verifies")
+	}
+}
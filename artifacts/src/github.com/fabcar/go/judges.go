@@ -0,0 +1,504 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	judgeObjectType           = "judge"
+	judgeAssignmentObjectType = "judgeAssignment"
+	// judgeCaseObjectType keys index the cases a judge is currently assigned to
+	judgeCaseObjectType = "judgeCase"
+
+	JudgeAssigned   = "ASSIGNED"
+	JudgeUnassigned = "UNASSIGNED"
+	JudgeRecused    = "RECUSED"
+	// JudgeConflictFlagged entries record conflicts that arose after the judge was assigned
+	JudgeConflictFlagged = "CONFLICT_FLAGGED"
+)
+
+// Judge is a registered judge of a court. EnrollmentID and MSPID bind the judge to the client
+// identity the judge signs transactions with.
+type Judge struct {
+	ID            string              `json:"id"`
+	Name          string              `json:"name"`
+	CourtID       string              `json:"courtID"`
+	Active        bool                `json:"active"`
+	EnrollmentID  string              `json:"enrollmentID"`
+	MSPID         string              `json:"mspID"`
+	CaseLoad      int                 `json:"caseLoad"` // counted from the judge case index when queried, never stored
+	Relationships []JudgeRelationship `json:"relationships,omitempty" metadata:"relationships,optional"`
+}
+
+// JudgeAssignment is one entry in the history of judge assignments of a case
+type JudgeAssignment struct {
+	CaseID    string   `json:"caseID"`
+	JudgeID   string   `json:"judgeID"`
+	Action    string   `json:"action"`
+	By        string   `json:"by"`
+	At        string   `json:"at"`
+	TxID      string   `json:"txID"`
+	Reason    string   `json:"reason"`
+	Conflicts []string `json:"conflicts,omitempty" metadata:"conflicts,optional"`
+}
+
+// CreateJudge registers a judge of a court owned by the caller's organization
+func (s *SmartContract) CreateJudge(ctx contractapi.TransactionContextInterface, judgeData string) (string, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return "", err
+	}
+	if len(judgeData) == 0 {
+		return "", fmt.Errorf("Please pass the correct judge data")
+	}
+
+	var judge Judge
+	err = json.Unmarshal([]byte(judgeData), &judge)
+	if err != nil {
+		return "", fmt.Errorf("Failed while unmarshalling judge. %s", err.Error())
+	}
+	if len(judge.ID) == 0 || len(judge.Name) == 0 {
+		return "", fmt.Errorf("Judge id and name are required")
+	}
+
+	court, err := getCourt(ctx, judge.CourtID)
+	if err != nil {
+		return "", err
+	}
+	err = requireCourtOwner(ctx, court.OwnerMSP)
+	if err != nil {
+		return "", err
+	}
+	if len(judge.MSPID) == 0 {
+		judge.MSPID = court.OwnerMSP
+	}
+
+	judgeKey, err := ctx.GetStub().CreateCompositeKey(judgeObjectType, []string{judge.ID})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	existing, err := ctx.GetStub().GetState(judgeKey)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if existing != nil {
+		return "", fmt.Errorf("Judge %s already exists", judge.ID)
+	}
+
+	judgeAsBytes, err := putJudge(ctx, &judge)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.GetStub().SetEvent("CreateJudge", judgeAsBytes)
+
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// UpdateJudge updates the name, active status, enrollment binding or relationships of a judge
+func (s *SmartContract) UpdateJudge(ctx contractapi.TransactionContextInterface, judgeID string, updateFieldsJSON string) error {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return err
+	}
+
+	judge, err := getJudge(ctx, judgeID)
+	if err != nil {
+		return err
+	}
+	court, err := getCourt(ctx, judge.CourtID)
+	if err != nil {
+		return err
+	}
+	err = requireCourtOwner(ctx, court.OwnerMSP)
+	if err != nil {
+		return err
+	}
+
+	var updateFields map[string]interface{}
+	err = json.Unmarshal([]byte(updateFieldsJSON), &updateFields)
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal update fields: %s", err.Error())
+	}
+
+	for field, value := range updateFields {
+		switch field {
+		case "name":
+			judge.Name = value.(string)
+		case "active":
+			judge.Active = value.(bool)
+		case "enrollmentID":
+			judge.EnrollmentID = value.(string)
+		case "mspID":
+			judge.MSPID = value.(string)
+		case "relationships":
+			// Replace the whole list; re-marshal so the entries are decoded into JudgeRelationship
+			relationshipsAsBytes, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("Failed to marshal relationships: %s", err.Error())
+			}
+			var relationships []JudgeRelationship
+			err = json.Unmarshal(relationshipsAsBytes, &relationships)
+			if err != nil {
+				return fmt.Errorf("Failed to unmarshal relationships: %s", err.Error())
+			}
+			judge.Relationships = relationships
+		default:
+			return fmt.Errorf("Invalid field name: %s", field)
+		}
+	}
+
+	judgeAsBytes, err := putJudge(ctx, judge)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("UpdateJudge", judgeAsBytes)
+}
+
+func (s *SmartContract) QueryJudge(ctx contractapi.TransactionContextInterface, judgeID string) (*Judge, error) {
+	judge, err := getJudge(ctx, judgeID)
+	if err != nil {
+		return nil, err
+	}
+	err = setJudgeCaseLoad(ctx, judge)
+	if err != nil {
+		return nil, err
+	}
+	return judge, nil
+}
+
+// QueryJudgesByCourt lists the judges registered for a court
+func (s *SmartContract) QueryJudgesByCourt(ctx contractapi.TransactionContextInterface, courtID string) ([]*Judge, error) {
+	return getJudgesByCourt(ctx, courtID)
+}
+
+// AssignJudge assigns a registered, active judge of the case's court to a legal record
+func (s *SmartContract) AssignJudge(ctx contractapi.TransactionContextInterface, caseID string, judgeID string) error {
+	legalRecord, err := getLegalRecordForJudgeChange(ctx, caseID)
+	if err != nil {
+		return err
+	}
+
+	err = assignJudge(ctx, legalRecord, judgeID, "")
+	if err != nil {
+		return err
+	}
+
+	_, err = putLegalRecord(ctx, legalRecord)
+	return err
+}
+
+// UnassignJudge removes a judge from a legal record
+func (s *SmartContract) UnassignJudge(ctx contractapi.TransactionContextInterface, caseID string, judgeID string, reason string) error {
+	legalRecord, err := getLegalRecordForJudgeChange(ctx, caseID)
+	if err != nil {
+		return err
+	}
+
+	err = unassignJudge(ctx, legalRecord, judgeID, JudgeUnassigned, reason)
+	if err != nil {
+		return err
+	}
+
+	_, err = putLegalRecord(ctx, legalRecord)
+	return err
+}
+
+// GetJudgeAssignmentHistory returns who assigned and unassigned which judges on a case, oldest first
+func (s *SmartContract) GetJudgeAssignmentHistory(ctx contractapi.TransactionContextInterface, caseID string) ([]*JudgeAssignment, error) {
+	if len(caseID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct case id")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(judgeAssignmentObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var assignments []*JudgeAssignment
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var assignment JudgeAssignment
+		err = json.Unmarshal(queryResponse.Value, &assignment)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal judge assignment. %s", err.Error())
+		}
+		assignments = append(assignments, &assignment)
+	}
+
+	return assignments, nil
+}
+
+// getLegalRecordForJudgeChange loads a legal record after checking the caller may change its judges
+func getLegalRecordForJudgeChange(ctx contractapi.TransactionContextInterface, caseID string) (*LegalRecord, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if len(legalRecord.OwnerMSP) > 0 {
+		err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return legalRecord, nil
+}
+
+// assignJudge validates the judge against the record's court and the conflict-of-interest rules, adds
+// it to the record and records the assignment. The caller still has to put the legal record.
+func assignJudge(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, judgeID string, reason string) error {
+	judge, err := getJudge(ctx, judgeID)
+	if err != nil {
+		return err
+	}
+	if !judge.Active {
+		return fmt.Errorf("Judge %s is not active", judgeID)
+	}
+	if len(legalRecord.CourtID) > 0 && judge.CourtID != legalRecord.CourtID {
+		return fmt.Errorf("Judge %s does not belong to court %s", judgeID, legalRecord.CourtID)
+	}
+	if isJudgeAssigned(legalRecord, judgeID) {
+		return fmt.Errorf("Judge %s is already assigned to %s", judgeID, legalRecord.CaseID)
+	}
+
+	conflicts, err := checkJudgeConflicts(legalRecord, judge)
+	if err != nil {
+		return err
+	}
+
+	legalRecord.Judges = append(legalRecord.Judges, judgeID)
+	return putJudgeAssignment(ctx, legalRecord.CaseID, judgeID, JudgeAssigned, reason, conflicts)
+}
+
+// unassignJudge removes the judge from the record and records the change under the given action.
+// The caller still has to put the legal record.
+func unassignJudge(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, judgeID string, action string, reason string) error {
+	var judges []string
+	for _, assigned := range legalRecord.Judges {
+		if assigned != judgeID {
+			judges = append(judges, assigned)
+		}
+	}
+	if len(judges) == len(legalRecord.Judges) {
+		return fmt.Errorf("Judge %s is not assigned to %s", judgeID, legalRecord.CaseID)
+	}
+	if judges == nil {
+		judges = []string{}
+	}
+
+	legalRecord.Judges = judges
+	return putJudgeAssignment(ctx, legalRecord.CaseID, judgeID, action, reason, nil)
+}
+
+func putJudgeAssignment(ctx contractapi.TransactionContextInterface, caseID string, judgeID string, action string, reason string, conflicts []string) error {
+	by, err := getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	at, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	assignment := JudgeAssignment{
+		CaseID:    caseID,
+		JudgeID:   judgeID,
+		Action:    action,
+		By:        by,
+		At:        at,
+		TxID:      ctx.GetStub().GetTxID(),
+		Reason:    reason,
+		Conflicts: conflicts,
+	}
+
+	assignmentKey, err := ctx.GetStub().CreateCompositeKey(judgeAssignmentObjectType, []string{caseID, at, assignment.TxID, judgeID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	assignmentAsBytes, err := json.Marshal(assignment)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal judge assignment. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(assignmentKey, assignmentAsBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to put judge assignment. %s", err.Error())
+	}
+
+	if len(conflicts) > 0 {
+		return ctx.GetStub().SetEvent("JudgeConflictFlagged", assignmentAsBytes)
+	}
+	return ctx.GetStub().SetEvent("JudgeAssignment", assignmentAsBytes)
+}
+
+func getJudge(ctx contractapi.TransactionContextInterface, judgeID string) (*Judge, error) {
+	if len(judgeID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct judge id")
+	}
+
+	judgeKey, err := ctx.GetStub().CreateCompositeKey(judgeObjectType, []string{judgeID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	judgeAsBytes, err := ctx.GetStub().GetState(judgeKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if judgeAsBytes == nil {
+		return nil, fmt.Errorf("Judge %s is not registered", judgeID)
+	}
+
+	judge := new(Judge)
+	err = json.Unmarshal(judgeAsBytes, judge)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal judge. %s", err.Error())
+	}
+	return judge, nil
+}
+
+func putJudge(ctx contractapi.TransactionContextInterface, judge *Judge) ([]byte, error) {
+	judgeKey, err := ctx.GetStub().CreateCompositeKey(judgeObjectType, []string{judge.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	// The case load is derived from the judge case index, so assignments never write the judge
+	stored := *judge
+	stored.CaseLoad = 0
+	judgeAsBytes, err := json.Marshal(stored)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal judge. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(judgeKey, judgeAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put judge. %s", err.Error())
+	}
+	return judgeAsBytes, nil
+}
+
+func getJudgesByCourt(ctx contractapi.TransactionContextInterface, courtID string) ([]*Judge, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(judgeObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var judges []*Judge
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var judge Judge
+		err = json.Unmarshal(queryResponse.Value, &judge)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal judge. %s", err.Error())
+		}
+		if judge.CourtID != courtID {
+			continue
+		}
+		err = setJudgeCaseLoad(ctx, &judge)
+		if err != nil {
+			return nil, err
+		}
+		judges = append(judges, &judge)
+	}
+
+	return judges, nil
+}
+
+// indexJudgeCases updates the index of cases by judge for the judges added to or removed from a
+// legal record since it was last stored
+func indexJudgeCases(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) error {
+	var stored []string
+	storedAsBytes, err := ctx.GetStub().GetState(legalRecord.CaseID)
+	if err != nil {
+		return fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if storedAsBytes != nil {
+		previous := new(LegalRecord)
+		err = json.Unmarshal(storedAsBytes, previous)
+		if err != nil {
+			return fmt.Errorf("Failed to unmarshal legal record. %s", err.Error())
+		}
+		stored = previous.Judges
+	}
+
+	for _, judgeID := range stored {
+		if isJudgeAssigned(legalRecord, judgeID) {
+			continue
+		}
+		judgeCaseKey, err := ctx.GetStub().CreateCompositeKey(judgeCaseObjectType, []string{judgeID, legalRecord.CaseID})
+		if err != nil {
+			return fmt.Errorf("Failed to create composite key. %s", err.Error())
+		}
+		err = ctx.GetStub().DelState(judgeCaseKey)
+		if err != nil {
+			return fmt.Errorf("Failed to delete judge case index. %s", err.Error())
+		}
+	}
+	for _, judgeID := range legalRecord.Judges {
+		if containsString(stored, judgeID) {
+			continue
+		}
+		judgeCaseKey, err := ctx.GetStub().CreateCompositeKey(judgeCaseObjectType, []string{judgeID, legalRecord.CaseID})
+		if err != nil {
+			return fmt.Errorf("Failed to create composite key. %s", err.Error())
+		}
+		// The entry only needs its key, but hold a JSON value like every other state
+		err = ctx.GetStub().PutState(judgeCaseKey, []byte("{}"))
+		if err != nil {
+			return fmt.Errorf("Failed to put judge case index. %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// getJudgeCaseIDs returns the IDs of the cases a judge is indexed as assigned to
+func getJudgeCaseIDs(ctx contractapi.TransactionContextInterface, judgeID string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(judgeCaseObjectType, []string{judgeID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	caseIDs := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to split composite key. %s", err.Error())
+		}
+		caseIDs = append(caseIDs, attributes[1])
+	}
+	return caseIDs, nil
+}
+
+// setJudgeCaseLoad sets the case load of a judge to the number of cases it is indexed as assigned to
+func setJudgeCaseLoad(ctx contractapi.TransactionContextInterface, judge *Judge) error {
+	caseIDs, err := getJudgeCaseIDs(ctx, judge.ID)
+	if err != nil {
+		return err
+	}
+	judge.CaseLoad = len(caseIDs)
+	return nil
+}
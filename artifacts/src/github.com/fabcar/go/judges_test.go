@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func queryJudge(e *testEnv, judgeID string) *Judge {
+	e.t.Helper()
+	judge := new(Judge)
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryJudge", judgeID)), judge)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return judge
+}
+
+func TestCreateJudge(t *testing.T) {
+	e := newTestEnv(t)
+
+	// Judges are registered by the organization owning their court only
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT1","active":true}`)
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT1","active":true}`)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("CreateJudge", `{"id":"k","courtID":"CT1","active":true}`)
+	e.mustFail("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT404","active":true}`)
+	e.mustFail("CreateJudge", `{"id":"j","name":"Judy","courtID":"CT1","active":true}`)
+	e.mustInvoke("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT1","active":true,"enrollmentID":"k","caseLoad":7}`)
+
+	judge := queryJudge(e, "k")
+	if judge.MSPID != "Org1MSP" || judge.CaseLoad != 0 || !judge.Active {
+		t.Fatalf("unexpected judge %+v", judge)
+	}
+}
+
+func TestAssignJudge(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"m","name":"Max","courtID":"CT2","active":true}`)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"r","name":"Retired","courtID":"CT1","active":false}`)
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}}))
+
+	e.mustFail("AssignJudge", "C1", "m")
+	e.mustFail("AssignJudge", "C1", "r")
+	e.mustFail("AssignJudge", "C1", "nobody")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("AssignJudge", "C1", "j")
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("AssignJudge", "C1", "j")
+	e.mustFail("AssignJudge", "C1", "j")
+	e.mustFail("UnassignJudge", "C1", "r", "mistake")
+	e.mustInvoke("UnassignJudge", "C1", "j", "reassigned")
+
+	var history []*JudgeAssignment
+	err := json.Unmarshal([]byte(e.mustInvoke("GetJudgeAssignmentHistory", "C1")), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Action != JudgeAssigned || history[1].Action != JudgeUnassigned || history[1].Reason != "reassigned" || history[0].By != "admin1" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
+
+func TestJudgeCaseLoad(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	judgeKey, err := e.stub.CreateCompositeKey(judgeObjectType, []string{"j"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered := string(e.stub.State[judgeKey])
+
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{"j"}}))
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C2", "courtID": "CT1", "judges": []string{}}))
+	e.mustInvoke("AssignJudge", "C2", "j")
+	if judge := queryJudge(e, "j"); judge.CaseLoad != 2 {
+		t.Fatalf("case load %d after two assignments", judge.CaseLoad)
+	}
+
+	// Assignments of different cases never write the same key
+	if string(e.stub.State[judgeKey]) != registered {
+		t.Fatalf("assignment wrote the judge %s", e.stub.State[judgeKey])
+	}
+
+	e.mustInvoke("UnassignJudge", "C1", "j", "reassigned")
+	var judges []*Judge
+	err = json.Unmarshal([]byte(e.mustInvoke("QueryJudgesByCourt", "CT1")), &judges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(judges) != 1 || judges[0].CaseLoad != 1 {
+		t.Fatalf("unexpected judges %+v", judges)
+	}
+
+	// Consolidating moves the judge to the lead case without counting it twice
+	e.mustInvoke("AssignJudge", "C1", "j")
+	e.mustInvoke("ConsolidateCases", "C1", `["C2"]`)
+	if judge := queryJudge(e, "j"); judge.CaseLoad != 1 {
+		t.Fatalf("case load %d after consolidation", judge.CaseLoad)
+	}
+}
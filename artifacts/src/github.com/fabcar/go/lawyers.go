@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	lawyerObjectType = "lawyer"
+
+	// barAssociationRole is the only role allowed to register lawyers and change their standing
+	barAssociationRole = "barAssociation"
+
+	LawyerActive    = "ACTIVE"
+	LawyerSuspended = "SUSPENDED"
+	LawyerDisbarred = "DISBARRED"
+)
+
+// Lawyer is a bar registration, keyed by jurisdiction and bar number. Only ACTIVE lawyers can be
+// added as counsel of a case.
+type Lawyer struct {
+	BarNumber    string `json:"barNumber"`
+	Jurisdiction string `json:"jurisdiction"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	StatusReason string `json:"statusReason,omitempty" metadata:"statusReason,optional"`
+	UpdatedBy    string `json:"updatedBy"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+// RegisterLawyer adds a lawyer to the bar registry
+func (s *SmartContract) RegisterLawyer(ctx contractapi.TransactionContextInterface, lawyerData string) (string, error) {
+	_, err := requireRole(ctx, barAssociationRole)
+	if err != nil {
+		return "", err
+	}
+	if len(lawyerData) == 0 {
+		return "", fmt.Errorf("Please pass the correct lawyer data")
+	}
+
+	var lawyer Lawyer
+	err = json.Unmarshal([]byte(lawyerData), &lawyer)
+	if err != nil {
+		return "", fmt.Errorf("Failed while unmarshalling lawyer. %s", err.Error())
+	}
+	if len(lawyer.BarNumber) == 0 || len(lawyer.Jurisdiction) == 0 || len(lawyer.Name) == 0 {
+		return "", fmt.Errorf("Lawyer bar number, jurisdiction and name are required")
+	}
+	lawyer.Jurisdiction = strings.ToUpper(lawyer.Jurisdiction)
+	lawyer.Status = strings.ToUpper(lawyer.Status)
+	if len(lawyer.Status) == 0 {
+		lawyer.Status = LawyerActive
+	}
+	if !isValidLawyerStatus(lawyer.Status) {
+		return "", fmt.Errorf("Invalid lawyer status: %s", lawyer.Status)
+	}
+
+	existing, err := getLawyer(ctx, lawyer.Jurisdiction, lawyer.BarNumber)
+	if err == nil && existing != nil {
+		return "", fmt.Errorf("Lawyer %s is already registered in %s", lawyer.BarNumber, lawyer.Jurisdiction)
+	}
+
+	lawyerAsBytes, err := putLawyer(ctx, &lawyer)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.GetStub().SetEvent("RegisterLawyer", lawyerAsBytes)
+
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// UpdateLawyerStatus suspends, disbars or reinstates a registered lawyer
+func (s *SmartContract) UpdateLawyerStatus(ctx contractapi.TransactionContextInterface, jurisdiction string, barNumber string, status string, reason string) error {
+	_, err := requireRole(ctx, barAssociationRole)
+	if err != nil {
+		return err
+	}
+
+	lawyer, err := getLawyer(ctx, jurisdiction, barNumber)
+	if err != nil {
+		return err
+	}
+
+	status = strings.ToUpper(status)
+	if !isValidLawyerStatus(status) {
+		return fmt.Errorf("Invalid lawyer status: %s", status)
+	}
+	if status != LawyerActive && len(reason) == 0 {
+		return fmt.Errorf("Please pass a reason for the status change")
+	}
+	lawyer.Status = status
+	lawyer.StatusReason = reason
+
+	lawyerAsBytes, err := putLawyer(ctx, lawyer)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("UpdateLawyerStatus", lawyerAsBytes)
+}
+
+func (s *SmartContract) QueryLawyer(ctx contractapi.TransactionContextInterface, jurisdiction string, barNumber string) (*Lawyer, error) {
+	return getLawyer(ctx, jurisdiction, barNumber)
+}
+
+// QueryLawyersByJurisdiction returns all lawyers registered in a jurisdiction
+func (s *SmartContract) QueryLawyersByJurisdiction(ctx contractapi.TransactionContextInterface, jurisdiction string) ([]*Lawyer, error) {
+	if len(jurisdiction) == 0 {
+		return nil, fmt.Errorf("Please pass the correct jurisdiction")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(lawyerObjectType, []string{strings.ToUpper(jurisdiction)})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var lawyers []*Lawyer
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var lawyer Lawyer
+		err = json.Unmarshal(queryResponse.Value, &lawyer)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal lawyer. %s", err.Error())
+		}
+		lawyers = append(lawyers, &lawyer)
+	}
+
+	return lawyers, nil
+}
+
+// requireActiveCounsel checks that a counsel participant is a registered lawyer in good standing
+func requireActiveCounsel(ctx contractapi.TransactionContextInterface, participant *Participant) error {
+	if participant.Role != ParticipantCounsel {
+		return nil
+	}
+	if len(participant.BarJurisdiction) == 0 {
+		return fmt.Errorf("Counsel %s must have a bar jurisdiction", participant.ID)
+	}
+
+	lawyer, err := getLawyer(ctx, participant.BarJurisdiction, participant.BarNumber)
+	if err != nil {
+		return err
+	}
+	if lawyer.Status != LawyerActive {
+		return fmt.Errorf("Lawyer %s in %s is %s and cannot act as counsel", lawyer.BarNumber, lawyer.Jurisdiction, strings.ToLower(lawyer.Status))
+	}
+	return nil
+}
+
+func isValidLawyerStatus(status string) bool {
+	return status == LawyerActive || status == LawyerSuspended || status == LawyerDisbarred
+}
+
+func getLawyer(ctx contractapi.TransactionContextInterface, jurisdiction string, barNumber string) (*Lawyer, error) {
+	if len(jurisdiction) == 0 || len(barNumber) == 0 {
+		return nil, fmt.Errorf("Please pass the correct jurisdiction and bar number")
+	}
+
+	lawyerKey, err := ctx.GetStub().CreateCompositeKey(lawyerObjectType, []string{strings.ToUpper(jurisdiction), barNumber})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	lawyerAsBytes, err := ctx.GetStub().GetState(lawyerKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if lawyerAsBytes == nil {
+		return nil, fmt.Errorf("Bar number %s is not registered in %s", barNumber, jurisdiction)
+	}
+
+	lawyer := new(Lawyer)
+	err = json.Unmarshal(lawyerAsBytes, lawyer)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal lawyer. %s", err.Error())
+	}
+	return lawyer, nil
+}
+
+// putLawyer stamps the lawyer with the caller and transaction time and writes it to the registry
+func putLawyer(ctx contractapi.TransactionContextInterface, lawyer *Lawyer) ([]byte, error) {
+	updatedBy, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lawyer.UpdatedBy = updatedBy
+	lawyer.UpdatedAt = updatedAt
+
+	lawyerKey, err := ctx.GetStub().CreateCompositeKey(lawyerObjectType, []string{lawyer.Jurisdiction, lawyer.BarNumber})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	lawyerAsBytes, err := json.Marshal(lawyer)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal lawyer. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(lawyerKey, lawyerAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put lawyer. %s", err.Error())
+	}
+	return lawyerAsBytes, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func queryLawyer(e *testEnv, jurisdiction string, barNumber string) *Lawyer {
+	e.t.Helper()
+	lawyer := new(Lawyer)
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryLawyer", jurisdiction, barNumber)), lawyer)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return lawyer
+}
+
+func TestRegisterLawyer(t *testing.T) {
+	e := newTestEnv(t)
+
+	for _, role := range []string{"approver", "lawyer", "clerk"} {
+		e.as("Org1MSP", "eve", role)
+		e.mustFail("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+	}
+
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustFail("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY"}`)
+	e.mustFail("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law","status":"retired"}`)
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"ny","name":"Bob Law"}`)
+	e.mustFail("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY2","jurisdiction":"NY","name":"Nora Law","status":"suspended"}`)
+
+	// Bar associations of other organizations share the registry
+	e.as("Org2MSP", "bar2", "barAssociation")
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NJ","name":"Bob Law"}`)
+
+	lawyer := queryLawyer(e, "ny", "NY1")
+	if lawyer.Jurisdiction != "NY" || lawyer.Status != LawyerActive || lawyer.UpdatedBy != "bar1" {
+		t.Fatalf("unexpected lawyer %+v", lawyer)
+	}
+	e.mustFail("QueryLawyer", "CA", "NY1")
+
+	var lawyers []*Lawyer
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryLawyersByJurisdiction", "NY")), &lawyers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lawyers) != 2 || lawyers[1].Status != LawyerSuspended {
+		t.Fatalf("unexpected lawyers %+v", lawyers)
+	}
+}
+
+func TestUpdateLawyerStatus(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+
+	e.as("Org1MSP", "bob", "lawyer")
+	e.mustFail("UpdateLawyerStatus", "NY", "NY1", "active", "")
+
+	e.as("Org2MSP", "bar2", "barAssociation")
+	e.mustFail("UpdateLawyerStatus", "NY", "NY9", "suspended", "unpaid dues")
+	e.mustFail("UpdateLawyerStatus", "NY", "NY1", "retired", "moved away")
+	e.mustFail("UpdateLawyerStatus", "NY", "NY1", "suspended", "")
+	e.mustInvoke("UpdateLawyerStatus", "NY", "NY1", "suspended", "unpaid dues")
+	if lawyer := queryLawyer(e, "NY", "NY1"); lawyer.Status != LawyerSuspended || lawyer.StatusReason != "unpaid dues" || lawyer.UpdatedBy != "bar2" {
+		t.Fatalf("unexpected lawyer %+v", lawyer)
+	}
+
+	// Reinstatement needs no reason and clears the previous one
+	e.mustInvoke("UpdateLawyerStatus", "NY", "NY1", "active", "")
+	if lawyer := queryLawyer(e, "NY", "NY1"); lawyer.Status != LawyerActive || lawyer.StatusReason != "" {
+		t.Fatalf("unexpected lawyer %+v", lawyer)
+	}
+}
+
+func TestCounselRequiresActiveRegistration(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY2","jurisdiction":"NY","name":"Nora Law","status":"disbarred"}`)
+
+	counsel := func(barNumber string, jurisdiction string) string {
+		return toJSON(t, map[string]interface{}{
+			"caseID":  "C1",
+			"courtID": "CT1",
+			"judges":  []string{},
+			"participants": []map[string]interface{}{
+				{"id": "p1", "name": "Widget Co", "role": "defendant"},
+				{"id": "l1", "name": "Counsel", "role": "counsel", "barNumber": barNumber, "barJurisdiction": jurisdiction, "represents": []string{"p1"}},
+			},
+		})
+	}
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("CreateLegalRecord", counsel("NY1", ""))
+	e.mustFail("CreateLegalRecord", counsel("NY1", "NJ"))
+	if msg := e.mustFail("CreateLegalRecord", counsel("NY2", "NY")); !strings.Contains(msg, "disbarred") {
+		t.Fatal(msg)
+	}
+	e.mustInvoke("CreateLegalRecord", counsel("NY1", "ny"))
+
+	// Suspended lawyers cannot be added to further cases
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("UpdateLawyerStatus", "NY", "NY1", "suspended", "unpaid dues")
+	e.as("Org1MSP", "admin1", "approver")
+	if msg := e.mustFail("AddParticipant", "C1", `{"id":"l2","name":"Bob Law","role":"counsel","barNumber":"NY1","barJurisdiction":"NY","represents":["p1"]}`); !strings.Contains(msg, "suspended") {
+		t.Fatal(msg)
+	}
+}
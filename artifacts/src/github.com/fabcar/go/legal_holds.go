@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	legalHoldObjectType = "legalHold"
+
+	LegalHoldActive   = "ACTIVE"
+	LegalHoldReleased = "RELEASED"
+)
+
+// LegalHold freezes a case while it is active. Holds are kept after release so the history of a
+// case shows every hold placed on it.
+type LegalHold struct {
+	HoldID          string `json:"holdID"`
+	CaseID          string `json:"caseID"`
+	Reason          string `json:"reason"`
+	OrderRef        string `json:"orderRef"`
+	Status          string `json:"status"`
+	PlacedBy        string `json:"placedBy"`
+	PlacedAt        string `json:"placedAt"`
+	ReleasedBy      string `json:"releasedBy,omitempty" metadata:"releasedBy,optional"`
+	ReleasedAt      string `json:"releasedAt,omitempty" metadata:"releasedAt,optional"`
+	ReleaseReason   string `json:"releaseReason,omitempty" metadata:"releaseReason,optional"`
+	ReleaseOrderRef string `json:"releaseOrderRef,omitempty" metadata:"releaseOrderRef,optional"`
+}
+
+// LegalHoldError is returned by every attempt to change a legal record under an active hold
+type LegalHoldError struct {
+	CaseID   string
+	HoldID   string
+	OrderRef string
+}
+
+func (e *LegalHoldError) Error() string {
+	return fmt.Sprintf("%s is under legal hold %s (order %s) and cannot be changed", e.CaseID, e.HoldID, e.OrderRef)
+}
+
+// PlaceLegalHold freezes a case on the authority of the referenced order. While the hold is active
+// nothing can be written to the legal record, its docket, filings, documents or transfers, so it cannot
+// be updated, transferred or consolidated, or change access, documents, orders, participants or judges.
+func (s *SmartContract) PlaceLegalHold(ctx contractapi.TransactionContextInterface, caseID string, reason string, orderRef string) (string, error) {
+	legalRecord, err := getLegalRecordForHoldChange(ctx, caseID, reason, orderRef)
+	if err != nil {
+		return "", err
+	}
+	active, err := getActiveLegalHold(ctx, caseID)
+	if err != nil {
+		return "", err
+	}
+	if active != nil {
+		return "", &LegalHoldError{CaseID: caseID, HoldID: active.HoldID, OrderRef: active.OrderRef}
+	}
+
+	placedBy, err := getClientName(ctx)
+	if err != nil {
+		return "", err
+	}
+	placedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	hold := &LegalHold{
+		HoldID:   ctx.GetStub().GetTxID(),
+		CaseID:   legalRecord.CaseID,
+		Reason:   reason,
+		OrderRef: orderRef,
+		Status:   LegalHoldActive,
+		PlacedBy: placedBy,
+		PlacedAt: placedAt,
+	}
+	holdAsBytes, err := putLegalHold(ctx, hold)
+	if err != nil {
+		return "", err
+	}
+	_, err = writeDocketEntry(ctx, caseID, &DocketEntry{
+		Type:        DocketNotice,
+		Description: fmt.Sprintf("Legal hold placed: %s", reason),
+		DocumentRef: orderRef,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	logger.Infof("Legal hold %s placed on %s by order %s", hold.HoldID, caseID, orderRef)
+	ctx.GetStub().SetEvent("PlaceLegalHold", holdAsBytes)
+
+	return hold.HoldID, nil
+}
+
+// ReleaseLegalHold lifts the active hold of a case on the authority of the referenced order
+func (s *SmartContract) ReleaseLegalHold(ctx contractapi.TransactionContextInterface, caseID string, reason string, orderRef string) error {
+	_, err := getLegalRecordForHoldChange(ctx, caseID, reason, orderRef)
+	if err != nil {
+		return err
+	}
+	hold, err := getActiveLegalHold(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if hold == nil {
+		return fmt.Errorf("%s is not under legal hold", caseID)
+	}
+
+	hold.Status = LegalHoldReleased
+	hold.ReleasedBy, err = getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	hold.ReleasedAt, err = getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	hold.ReleaseReason = reason
+	hold.ReleaseOrderRef = orderRef
+
+	holdAsBytes, err := putLegalHold(ctx, hold)
+	if err != nil {
+		return err
+	}
+	_, err = writeDocketEntry(ctx, caseID, &DocketEntry{
+		Type:        DocketNotice,
+		Description: fmt.Sprintf("Legal hold released: %s", reason),
+		DocumentRef: orderRef,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Legal hold %s on %s released by order %s", hold.HoldID, caseID, orderRef)
+	return ctx.GetStub().SetEvent("ReleaseLegalHold", holdAsBytes)
+}
+
+// GetLegalHolds returns all holds placed on a case, including released ones
+func (s *SmartContract) GetLegalHolds(ctx contractapi.TransactionContextInterface, caseID string) ([]*LegalHold, error) {
+	if len(caseID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct case id")
+	}
+	return getLegalHolds(ctx, caseID)
+}
+
+// requireNoLegalHold fails with a LegalHoldError when a case is under an active hold. It is called by
+// the helpers writing a case (putLegalRecord, appendDocketEntry, putFiling, putDocument and
+// putCaseTransfer), so transactions do not need to check the hold themselves.
+func requireNoLegalHold(ctx contractapi.TransactionContextInterface, caseID string) error {
+	hold, err := getActiveLegalHold(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if hold != nil {
+		return &LegalHoldError{CaseID: caseID, HoldID: hold.HoldID, OrderRef: hold.OrderRef}
+	}
+	return nil
+}
+
+// getLegalRecordForHoldChange checks the caller is an approver of the organization owning the case
+// and that a reason and order reference were given
+func getLegalRecordForHoldChange(ctx contractapi.TransactionContextInterface, caseID string, reason string, orderRef string) (*LegalRecord, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+	if len(reason) == 0 || len(orderRef) == 0 {
+		return nil, fmt.Errorf("Please pass a reason and the reference of the order")
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if len(legalRecord.OwnerMSP) > 0 {
+		err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return legalRecord, nil
+}
+
+func getActiveLegalHold(ctx contractapi.TransactionContextInterface, caseID string) (*LegalHold, error) {
+	holds, err := getLegalHolds(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	for _, hold := range holds {
+		if hold.Status == LegalHoldActive {
+			return hold, nil
+		}
+	}
+	return nil, nil
+}
+
+func getLegalHolds(ctx contractapi.TransactionContextInterface, caseID string) ([]*LegalHold, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(legalHoldObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	holds := []*LegalHold{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var hold LegalHold
+		err = json.Unmarshal(queryResponse.Value, &hold)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal legal hold. %s", err.Error())
+		}
+		holds = append(holds, &hold)
+	}
+
+	return holds, nil
+}
+
+func putLegalHold(ctx contractapi.TransactionContextInterface, hold *LegalHold) ([]byte, error) {
+	holdKey, err := ctx.GetStub().CreateCompositeKey(legalHoldObjectType, []string{hold.CaseID, hold.HoldID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	holdAsBytes, err := json.Marshal(hold)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal legal hold. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(holdKey, holdAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put legal hold. %s", err.Error())
+	}
+	return holdAsBytes, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newHeldRecordEnv(t *testing.T) (*testEnv, string) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":          "C1",
+		"courtID":         "CT1",
+		"judges":          []string{"j"},
+		"usersWithAccess": []string{},
+		"participants": []map[string]interface{}{
+			{"id": "p1", "name": "Widget Co", "role": "defendant"},
+		},
+	}))
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C2", "courtID": "CT1", "judges": []string{}}))
+	// A second judge of the court keeps AutoAssignJudge from running out of eligible judges
+	e.mustInvoke("CreateJudge", `{"id":"j2","name":"Jon","courtID":"CT1","active":true}`)
+	holdID := e.mustInvoke("PlaceLegalHold", "C1", "investigation", "ORD-1")
+	return e, holdID
+}
+
+func requireLegalHoldError(t *testing.T, fn string, msg string) {
+	t.Helper()
+	if !strings.Contains(msg, "under legal hold") {
+		t.Fatalf("%s failed without a legal hold error: %s", fn, msg)
+	}
+}
+
+func TestPlaceLegalHold(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}}))
+
+	e.mustFail("PlaceLegalHold", "C1", "", "ORD-1")
+	e.mustFail("PlaceLegalHold", "C1", "investigation", "")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("PlaceLegalHold", "C1", "investigation", "ORD-1")
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("PlaceLegalHold", "C1", "investigation", "ORD-1")
+
+	e.as("Org1MSP", "admin1", "approver")
+	holdID := e.mustInvoke("PlaceLegalHold", "C1", "investigation", "ORD-1")
+	if msg := e.mustFail("PlaceLegalHold", "C1", "again", "ORD-2"); !strings.Contains(msg, holdID) {
+		t.Fatal(msg)
+	}
+
+	var holds []*LegalHold
+	err := json.Unmarshal([]byte(e.mustInvoke("GetLegalHolds", "C1")), &holds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holds) != 1 || holds[0].HoldID != holdID || holds[0].Status != LegalHoldActive || holds[0].PlacedBy != "admin1" {
+		t.Fatalf("unexpected holds %+v", holds)
+	}
+}
+
+func TestLegalHoldBlocksChanges(t *testing.T) {
+	e, _ := newHeldRecordEnv(t)
+
+	for _, call := range [][]string{
+		{"UpdateLegalRecord", "C1", `{"status":"OPEN"}`},
+		{"AddParticipant", "C1", `{"id":"w1","name":"Wendy","role":"witness"}`},
+		{"RemoveParticipant", "C1", "p1"},
+		{"UnassignJudge", "C1", "j", "reassigned"},
+		{"RecuseJudge", "C1", "j", "conflict"},
+		{"AutoAssignJudge", "C1"},
+		{"InitiateCaseTransfer", "C1", "CT2", "", "venue"},
+		{"ConsolidateCases", "C2", `["C1"]`},
+		{"ConsolidateCases", "C1", `["C2"]`},
+		{"AddDocketEntry", "C1", `{"type":"notice","description":"Exhibit","documentRef":"doc1"}`},
+	} {
+		requireLegalHoldError(t, call[0], e.mustFail(call[0], call[1:]...))
+	}
+
+	// Entries that do not attach a document are frozen as well
+	requireLegalHoldError(t, "AddDocketEntry", e.mustFail("AddDocketEntry", "C1", `{"type":"minute","description":"Status conference held"}`))
+
+	e.as("Org1MSP", "j", "judge")
+	requireLegalHoldError(t, "IssueOrder", e.mustFail("IssueOrder", "C1", `{"type":"procedural","textHash":"`+strings.Repeat("ab", 32)+`"}`))
+
+	// Other cases are not affected
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("AddDocketEntry", "C2", `{"type":"minute","description":"Status conference held"}`)
+}
+
+func TestLegalHoldBlocksFilingReview(t *testing.T) {
+	e := newFilingEnv(t)
+	e.as("Org1MSP", "bob", "lawyer")
+	motionID := jsonField(t, e.mustInvoke("CreateFiling", "C1", `{"title":"Motion","documentRef":"doc1"}`), "recordID")
+	answerID := jsonField(t, e.mustInvoke("CreateFiling", "C1", `{"title":"Answer","documentRef":"doc2"}`), "recordID")
+	e.mustInvoke("SubmitFiling", "C1", motionID)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("PlaceLegalHold", "C1", "investigation", "ORD-1")
+
+	e.as("Org1MSP", "clerk1", "clerk")
+	requireLegalHoldError(t, "AcceptFiling", e.mustFail("AcceptFiling", "C1", motionID, ""))
+	requireLegalHoldError(t, "RejectFiling", e.mustFail("RejectFiling", "C1", motionID, "unsigned"))
+	e.as("Org1MSP", "bob", "lawyer")
+	requireLegalHoldError(t, "SubmitFiling", e.mustFail("SubmitFiling", "C1", answerID))
+	requireLegalHoldError(t, "CreateFiling", e.mustFail("CreateFiling", "C1", `{"title":"Reply","documentRef":"doc3"}`))
+
+	for _, filing := range queryFilings(e, "C1") {
+		if filing.FilingID == motionID && filing.Status != FilingSubmitted || filing.FilingID == answerID && filing.Status != FilingDraft {
+			t.Fatalf("held filing changed %+v", filing)
+		}
+	}
+}
+
+func TestEmergencyAccessOnHeldCase(t *testing.T) {
+	e, _ := newHeldRecordEnv(t)
+	before := e.stub.State["C1"]
+
+	e.as("Org1MSP", "off", "officer")
+	legalRecord := e.mustInvoke("EmergencyAccessLegalRecord", "C1", "imminent harm")
+	if jsonField(t, legalRecord, "caseID") != "C1" {
+		t.Fatal(legalRecord)
+	}
+
+	e.as("Org1MSP", "admin1", "approver")
+	if flagged := e.mustInvoke("QueryCasesFlaggedForReview"); flagged != `["C1"]` {
+		t.Fatalf("unexpected flagged cases %s", flagged)
+	}
+	var reports []*EmergencyAccessReport
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryEmergencyAccesses", "C1")), &reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Access.Officer != "off" {
+		t.Fatalf("unexpected emergency accesses %+v", reports)
+	}
+
+	// The review clears the flag without touching the held record
+	e.mustInvoke("ReviewEmergencyAccess", "C1", reports[0].Access.AccessID, "justified")
+	if flagged := e.mustInvoke("QueryCasesFlaggedForReview"); flagged != `[]` {
+		t.Fatalf("unexpected flagged cases %s", flagged)
+	}
+	if string(e.stub.State["C1"]) != string(before) {
+		t.Fatal("emergency access changed the held legal record")
+	}
+}
+
+func TestReleaseLegalHold(t *testing.T) {
+	e, holdID := newHeldRecordEnv(t)
+
+	e.mustFail("ReleaseLegalHold", "C1", "", "ORD-2")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("ReleaseLegalHold", "C1", "investigation closed", "ORD-2")
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("ReleaseLegalHold", "C1", "investigation closed", "ORD-2")
+	e.mustFail("ReleaseLegalHold", "C1", "again", "ORD-3")
+	e.mustInvoke("UpdateLegalRecord", "C1", `{"status":"OPEN"}`)
+	e.mustInvoke("AddParticipant", "C1", `{"id":"w1","name":"Wendy","role":"witness"}`)
+
+	var holds []*LegalHold
+	err := json.Unmarshal([]byte(e.mustInvoke("GetLegalHolds", "C1")), &holds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holds) != 1 || holds[0].HoldID != holdID || holds[0].Status != LegalHoldReleased || holds[0].ReleaseOrderRef != "ORD-2" {
+		t.Fatalf("unexpected holds %+v", holds)
+	}
+
+	// Placing and releasing the hold are both noted on the docket
+	var docket DocketPage
+	err = json.Unmarshal([]byte(e.mustInvoke("GetDocket", "C1", "0", "")), &docket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docket.Entries) != 2 || docket.Entries[0].DocumentRef != "ORD-1" || docket.Entries[1].DocumentRef != "ORD-2" {
+		t.Fatalf("unexpected docket %+v", docket.Entries)
+	}
+}
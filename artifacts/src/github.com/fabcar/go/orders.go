@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	orderObjectType             = "order"
+	orderSupersessionObjectType = "orderSupersession"
+
+	OrderProcedural = "PROCEDURAL"
+	OrderInterim    = "INTERIM"
+	OrderFinal      = "FINAL"
+	OrderJudgment   = "JUDGMENT"
+)
+
+// Order is an order or judgment issued in a case. The text itself stays off-chain; TextHash is the
+// hex SHA-256 of it. Orders are never modified: an order is vacated by issuing a new order that
+// supersedes it, which is recorded under a separate key and reported in VacatedBy. A verified judge
+// signature is likewise stored separately and reported in Signature.
+type Order struct {
+	OrderID       string          `json:"orderID"`
+	CaseID        string          `json:"caseID"`
+	Type          string          `json:"type"`
+	TextHash      string          `json:"textHash"`
+	JudgeID       string          `json:"judgeID"`
+	EffectiveDate string          `json:"effectiveDate"`
+	Supersedes    string          `json:"supersedes,omitempty" metadata:"supersedes,optional"`
+	IssuedBy      string          `json:"issuedBy"`
+	IssuedAt      string          `json:"issuedAt"`
+	DocketNumber  int             `json:"docketNumber"`
+	VacatedBy     string          `json:"vacatedBy,omitempty" metadata:"vacatedBy,optional"`
+	Signature     *OrderSignature `json:"signature,omitempty" metadata:"signature,optional"`
+}
+
+// IssueOrder issues an order in a case. It can only be called through the identity bound to a judge
+// assigned to the case. If Supersedes is set, the referenced order is vacated by the new one. Deadline
+// rules for the order type (e.g. JUDGMENT) are applied from the effective date.
+func (s *SmartContract) IssueOrder(ctx contractapi.TransactionContextInterface, caseID string, orderJSON string) (*Order, error) {
+	_, err := requireRole(ctx, "judge")
+	if err != nil {
+		return nil, err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	judge, err := getAssignedJudgeForClient(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	var order Order
+	err = json.Unmarshal([]byte(orderJSON), &order)
+	if err != nil {
+		return nil, fmt.Errorf("Failed while unmarshalling order. %s", err.Error())
+	}
+	order.Type = strings.ToUpper(order.Type)
+	switch order.Type {
+	case OrderProcedural, OrderInterim, OrderFinal, OrderJudgment:
+	default:
+		return nil, fmt.Errorf("Invalid order type: %s", order.Type)
+	}
+	textHash, err := hex.DecodeString(order.TextHash)
+	if err != nil || len(textHash) != 32 {
+		return nil, fmt.Errorf("Order text hash must be a hex encoded SHA-256 digest")
+	}
+
+	issuedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(order.EffectiveDate) == 0 {
+		order.EffectiveDate = issuedAt
+	}
+	effectiveDate, err := time.Parse(time.RFC3339, order.EffectiveDate)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid effective date %s. %s", order.EffectiveDate, err.Error())
+	}
+	issuedBy, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	order.OrderID = ctx.GetStub().GetTxID()
+	order.CaseID = caseID
+	order.TextHash = strings.ToLower(order.TextHash)
+	order.JudgeID = judge.ID
+	order.EffectiveDate = effectiveDate.UTC().Format(time.RFC3339)
+	order.IssuedBy = issuedBy
+	order.IssuedAt = issuedAt
+	order.VacatedBy = ""
+	order.Signature = nil
+
+	if len(order.Supersedes) > 0 {
+		err = supersedeOrder(ctx, caseID, order.Supersedes, order.OrderID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	description := fmt.Sprintf("Order %s (%s) issued by judge %s", order.OrderID, strings.ToLower(order.Type), judge.ID)
+	if len(order.Supersedes) > 0 {
+		description += fmt.Sprintf(", vacating order %s", order.Supersedes)
+	}
+	docketEntry := &DocketEntry{Type: DocketOrder, Description: description, DocumentRef: order.OrderID}
+	_, err = appendDocketEntry(ctx, caseID, docketEntry)
+	if err != nil {
+		return nil, err
+	}
+	order.DocketNumber = docketEntry.Number
+
+	orderKey, err := ctx.GetStub().CreateCompositeKey(orderObjectType, []string{caseID, order.OrderID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	orderAsBytes, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal order. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(orderKey, orderAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put order. %s", err.Error())
+	}
+
+	_, err = triggerDeadlines(ctx, legalRecord, order.Type, effectiveDate)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.GetStub().SetEvent("IssueOrder", orderAsBytes)
+
+	return &order, nil
+}
+
+// QueryOrder returns an order of a case, with VacatedBy set if it has been superseded and Signature
+// if the judge's signature has been verified
+func (s *SmartContract) QueryOrder(ctx contractapi.TransactionContextInterface, caseID string, orderID string) (*Order, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	return getOrder(ctx, caseID, orderID)
+}
+
+// QueryOrders returns all orders of a case in the order they were issued
+func (s *SmartContract) QueryOrders(ctx contractapi.TransactionContextInterface, caseID string) ([]*Order, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(orderObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	orders := []*Order{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var order Order
+		err = json.Unmarshal(queryResponse.Value, &order)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal order. %s", err.Error())
+		}
+		err = loadOrderStatus(ctx, &order)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, &order)
+	}
+
+	// Keys are ordered by transaction ID, so sort by docket number to list orders as issued
+	sort.Slice(orders, func(i, j int) bool { return orders[i].DocketNumber < orders[j].DocketNumber })
+
+	return orders, nil
+}
+
+// supersedeOrder records that an existing order of the case is vacated by a new one. An order can
+// only be superseded once.
+func supersedeOrder(ctx contractapi.TransactionContextInterface, caseID string, orderID string, supersedingOrderID string) error {
+	order, err := getOrder(ctx, caseID, orderID)
+	if err != nil {
+		return err
+	}
+	if len(order.VacatedBy) > 0 {
+		return fmt.Errorf("Order %s has already been vacated by order %s", orderID, order.VacatedBy)
+	}
+
+	supersessionKey, err := ctx.GetStub().CreateCompositeKey(orderSupersessionObjectType, []string{caseID, orderID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(supersessionKey, []byte(supersedingOrderID))
+	if err != nil {
+		return fmt.Errorf("Failed to put order supersession. %s", err.Error())
+	}
+	return nil
+}
+
+// getSupersedingOrderID returns the ID of the order that vacated an order, or an empty string
+func getSupersedingOrderID(ctx contractapi.TransactionContextInterface, caseID string, orderID string) (string, error) {
+	supersessionKey, err := ctx.GetStub().CreateCompositeKey(orderSupersessionObjectType, []string{caseID, orderID})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	supersedingAsBytes, err := ctx.GetStub().GetState(supersessionKey)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	return string(supersedingAsBytes), nil
+}
+
+func getOrder(ctx contractapi.TransactionContextInterface, caseID string, orderID string) (*Order, error) {
+	if len(caseID) == 0 || len(orderID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct case id and order id")
+	}
+
+	orderKey, err := ctx.GetStub().CreateCompositeKey(orderObjectType, []string{caseID, orderID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	orderAsBytes, err := ctx.GetStub().GetState(orderKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if orderAsBytes == nil {
+		return nil, fmt.Errorf("Order %s does not exist for %s", orderID, caseID)
+	}
+
+	order := new(Order)
+	err = json.Unmarshal(orderAsBytes, order)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal order. %s", err.Error())
+	}
+	err = loadOrderStatus(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// loadOrderStatus fills in the superseding order and verified signature, which are kept outside the
+// immutable order
+func loadOrderStatus(ctx contractapi.TransactionContextInterface, order *Order) error {
+	vacatedBy, err := getSupersedingOrderID(ctx, order.CaseID, order.OrderID)
+	if err != nil {
+		return err
+	}
+	signature, err := getOrderSignature(ctx, order.CaseID, order.OrderID)
+	if err != nil {
+		return err
+	}
+	order.VacatedBy = vacatedBy
+	order.Signature = signature
+	return nil
+}
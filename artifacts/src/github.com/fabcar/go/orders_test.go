@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newOrderEnv returns an environment with the sealed case C1 of CT1 assigned to judge j, judge k of
+// CT1 not assigned to it, and judge o of CT2
+func newOrderEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT1","active":true,"enrollmentID":"k"}`)
+	e.setPrivateDetails(map[string]string{"description": "Sealed matter"})
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":          "C1",
+		"courtID":         "CT1",
+		"judges":          []string{"j"},
+		"confidentiality": "SEALED",
+		"usersWithAccess": []string{"admin1"},
+	}))
+	e.setPrivateDetails(map[string]string{})
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("CreateJudge", `{"id":"o","name":"Otto","courtID":"CT2","active":true,"enrollmentID":"o"}`)
+	return e
+}
+
+func orderJSON(orderType string, supersedes string) string {
+	order := `{"type":"` + orderType + `","textHash":"` + strings.Repeat("AB", 32) + `"`
+	if len(supersedes) > 0 {
+		order += `,"supersedes":"` + supersedes + `"`
+	}
+	return order + "}"
+}
+
+func decodeOrder(t *testing.T, orderJSON string) *Order {
+	t.Helper()
+	order := new(Order)
+	err := json.Unmarshal([]byte(orderJSON), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestIssueOrder(t *testing.T) {
+	e := newOrderEnv(t)
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("IssueOrder", "C1", orderJSON("procedural", ""))
+	e.as("Org1MSP", "k", "judge")
+	e.mustFail("IssueOrder", "C1", orderJSON("procedural", ""))
+	e.as("Org2MSP", "o", "judge")
+	e.mustFail("IssueOrder", "C1", orderJSON("procedural", ""))
+	// The identity must be bound to the assigned judge, not only carry the judge role
+	e.as("Org1MSP", "jay", "judge")
+	e.mustFail("IssueOrder", "C1", orderJSON("procedural", ""))
+
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("IssueOrder", "C9", orderJSON("procedural", ""))
+	e.mustFail("IssueOrder", "C1", orderJSON("advisory", ""))
+	e.mustFail("IssueOrder", "C1", `{"type":"procedural","textHash":"abc"}`)
+	e.mustFail("IssueOrder", "C1", `{"type":"procedural","textHash":"`+strings.Repeat("ab", 32)+`","effectiveDate":"tomorrow"}`)
+
+	order := decodeOrder(t, e.mustInvoke("IssueOrder", "C1", orderJSON("interim", "")))
+	if order.Type != OrderInterim || order.JudgeID != "j" || order.IssuedBy != "j" || order.TextHash != strings.Repeat("ab", 32) || order.EffectiveDate != order.IssuedAt || order.DocketNumber != 1 {
+		t.Fatalf("unexpected order %+v", order)
+	}
+
+	e.as("Org1MSP", "admin1", "approver")
+	docket := getDocketPage(e, "C1", "0", "")
+	if len(docket.Entries) != 1 || docket.Entries[0].Type != DocketOrder || docket.Entries[0].DocumentRef != order.OrderID {
+		t.Fatalf("unexpected docket %+v", docket.Entries)
+	}
+}
+
+func TestSupersedeOrder(t *testing.T) {
+	e := newOrderEnv(t)
+	e.as("Org1MSP", "j", "judge")
+	first := decodeOrder(t, e.mustInvoke("IssueOrder", "C1", orderJSON("interim", "")))
+
+	e.mustFail("IssueOrder", "C1", orderJSON("final", "tx9999"))
+	second := decodeOrder(t, e.mustInvoke("IssueOrder", "C1", orderJSON("final", first.OrderID)))
+	if msg := e.mustFail("IssueOrder", "C1", orderJSON("final", first.OrderID)); !strings.Contains(msg, "already been vacated") {
+		t.Fatal(msg)
+	}
+
+	if vacated := decodeOrder(t, e.mustInvoke("QueryOrder", "C1", first.OrderID)); vacated.VacatedBy != second.OrderID {
+		t.Fatalf("unexpected order %+v", vacated)
+	}
+
+	var orders []*Order
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryOrders", "C1")), &orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].OrderID != first.OrderID || orders[1].OrderID != second.OrderID || orders[1].Supersedes != first.OrderID || orders[1].VacatedBy != "" {
+		t.Fatalf("unexpected orders %+v", orders)
+	}
+}
+
+func TestQueryOrdersAccess(t *testing.T) {
+	e := newOrderEnv(t)
+	e.as("Org1MSP", "j", "judge")
+	order := decodeOrder(t, e.mustInvoke("IssueOrder", "C1", orderJSON("procedural", "")))
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("QueryOrders", "C1")
+	e.mustInvoke("QueryOrder", "C1", order.OrderID)
+	e.mustFail("QueryOrder", "C1", "tx9999")
+
+	for _, caller := range [][]string{{"Org2MSP", "admin2", "approver"}, {"Org2MSP", "o", "judge"}, {"Org1MSP", "k", "judge"}, {"Org1MSP", "bob", "client"}} {
+		e.as(caller[0], caller[1], caller[2])
+		e.mustFail("QueryOrders", "C1")
+		e.mustFail("QueryOrder", "C1", order.OrderID)
+	}
+}
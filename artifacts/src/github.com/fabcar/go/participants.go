@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	ParticipantPlaintiff = "PLAINTIFF"
+	ParticipantDefendant = "DEFENDANT"
+	ParticipantWitness   = "WITNESS"
+	ParticipantCounsel   = "COUNSEL"
+)
+
+// Participant is a party, witness or counsel of a case. Counsel must be active in the bar registry
+// under BarJurisdiction and BarNumber, list the IDs of the participants they represent in Represents,
+// and gain read access to the non-sealed parts of the case through EnrollmentID.
+type Participant struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Role            string   `json:"role"`
+	BarNumber       string   `json:"barNumber,omitempty" metadata:"barNumber,optional"`
+	BarJurisdiction string   `json:"barJurisdiction,omitempty" metadata:"barJurisdiction,optional"`
+	Represents      []string `json:"represents,omitempty" metadata:"represents,optional"`
+	EnrollmentID    string   `json:"enrollmentID,omitempty" metadata:"enrollmentID,optional"`
+}
+
+// AddParticipant adds a party, witness or counsel to a legal record
+func (s *SmartContract) AddParticipant(ctx contractapi.TransactionContextInterface, caseID string, participantData string) error {
+	legalRecord, err := getLegalRecordForParticipantChange(ctx, caseID)
+	if err != nil {
+		return err
+	}
+
+	var participant Participant
+	err = json.Unmarshal([]byte(participantData), &participant)
+	if err != nil {
+		return fmt.Errorf("Failed while unmarshalling participant. %s", err.Error())
+	}
+
+	previous := legalRecord.Participants
+	legalRecord.Participants = append(append([]Participant{}, previous...), participant)
+	err = validateParticipants(legalRecord)
+	if err != nil {
+		return err
+	}
+	err = requireActiveCounsel(ctx, &legalRecord.Participants[len(legalRecord.Participants)-1])
+	if err != nil {
+		return err
+	}
+
+	// A new party or counsel may conflict with judges already on the case
+	err = flagAssignedJudgeConflicts(ctx, legalRecord, previous, "participant "+participant.ID+" added")
+	if err != nil {
+		return err
+	}
+
+	_, err = putLegalRecord(ctx, legalRecord)
+	if err != nil {
+		return err
+	}
+
+	participantAsBytes, err := json.Marshal(participant)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal participant. %s", err.Error())
+	}
+	return ctx.GetStub().SetEvent("AddParticipant", participantAsBytes)
+}
+
+// RemoveParticipant removes a participant from a legal record. A party that is still represented by
+// counsel cannot be removed until the counsel is removed or no longer represents it.
+func (s *SmartContract) RemoveParticipant(ctx contractapi.TransactionContextInterface, caseID string, participantID string) error {
+	legalRecord, err := getLegalRecordForParticipantChange(ctx, caseID)
+	if err != nil {
+		return err
+	}
+
+	var participants []Participant
+	for _, participant := range legalRecord.Participants {
+		if participant.ID != participantID {
+			participants = append(participants, participant)
+		}
+	}
+	if len(participants) == len(legalRecord.Participants) {
+		return fmt.Errorf("Participant %s is not part of %s", participantID, caseID)
+	}
+
+	legalRecord.Participants = participants
+	err = validateParticipants(legalRecord)
+	if err != nil {
+		return err
+	}
+
+	_, err = putLegalRecord(ctx, legalRecord)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("RemoveParticipant", []byte(participantID))
+}
+
+// getLegalRecordForParticipantChange loads a legal record after checking the caller may change its
+// participants
+func getLegalRecordForParticipantChange(ctx contractapi.TransactionContextInterface, caseID string) (*LegalRecord, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if len(legalRecord.OwnerMSP) > 0 {
+		err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return legalRecord, nil
+}
+
+// validateParticipants checks roles, unique IDs, and that counsel have a bar number and only
+// represent parties of the same case
+func validateParticipants(legalRecord *LegalRecord) error {
+	roles := make(map[string]string)
+	for i := range legalRecord.Participants {
+		participant := &legalRecord.Participants[i]
+		participant.Role = strings.ToUpper(participant.Role)
+
+		if len(participant.ID) == 0 || len(participant.Name) == 0 {
+			return fmt.Errorf("Participant id and name are required")
+		}
+		if _, ok := roles[participant.ID]; ok {
+			return fmt.Errorf("Participant %s is already part of %s", participant.ID, legalRecord.CaseID)
+		}
+		switch participant.Role {
+		case ParticipantPlaintiff, ParticipantDefendant, ParticipantWitness, ParticipantCounsel:
+		default:
+			return fmt.Errorf("Invalid participant role: %s", participant.Role)
+		}
+		roles[participant.ID] = participant.Role
+	}
+
+	for _, participant := range legalRecord.Participants {
+		if participant.Role != ParticipantCounsel {
+			if len(participant.Represents) > 0 {
+				return fmt.Errorf("Only counsel can represent other participants")
+			}
+			continue
+		}
+		if len(participant.BarNumber) == 0 {
+			return fmt.Errorf("Counsel %s must have a bar number", participant.ID)
+		}
+		for _, representedID := range participant.Represents {
+			role, ok := roles[representedID]
+			if !ok {
+				return fmt.Errorf("Counsel %s represents %s which is not part of %s", participant.ID, representedID, legalRecord.CaseID)
+			}
+			if !isParty(role) {
+				return fmt.Errorf("Counsel %s can only represent parties, not %s", participant.ID, representedID)
+			}
+		}
+	}
+	return nil
+}
+
+func isParty(role string) bool {
+	return role == ParticipantPlaintiff || role == ParticipantDefendant
+}
+
+// isCounselOfRecord reports whether username is bound to counsel on the case whose bar registration
+// is still active, so suspended or disbarred lawyers lose access as soon as their status changes
+func isCounselOfRecord(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, username string) bool {
+	for i := range legalRecord.Participants {
+		participant := &legalRecord.Participants[i]
+		if participant.Role != ParticipantCounsel || len(participant.EnrollmentID) == 0 || !strings.EqualFold(participant.EnrollmentID, username) {
+			continue
+		}
+		err := requireActiveCounsel(ctx, participant)
+		if err != nil {
+			logger.Debugf("Counsel %s of %s has no access: %s", participant.ID, legalRecord.CaseID, err.Error())
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+)
+
+func newParticipantEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":       "C1",
+		"courtID":      "CT1",
+		"judges":       []string{},
+		"participants": []map[string]interface{}{{"id": "p1", "name": "Widget Co", "role": "defendant"}},
+	}))
+	return e
+}
+
+func TestAddParticipant(t *testing.T) {
+	e := newParticipantEnv(t)
+
+	for _, participant := range []string{
+		`{"id":"p1","name":"Duplicate","role":"plaintiff"}`,
+		`{"id":"p2","name":"Acme","role":"party"}`,
+		`{"id":"p2","role":"plaintiff"}`,
+		`{"id":"w1","name":"Wendy","role":"witness","represents":["p1"]}`,
+		`{"id":"l1","name":"Bob Law","role":"counsel","barJurisdiction":"NY","represents":["p1"]}`,
+		`{"id":"l1","name":"Bob Law","role":"counsel","barNumber":"NY1","represents":["p1"]}`,
+		`{"id":"l1","name":"Bob Law","role":"counsel","barNumber":"NY2","barJurisdiction":"NY","represents":["p1"]}`,
+		`{"id":"l1","name":"Bob Law","role":"counsel","barNumber":"NY1","barJurisdiction":"NY","represents":["p9"]}`,
+	} {
+		e.mustFail("AddParticipant", "C1", participant)
+	}
+
+	e.mustInvoke("AddParticipant", "C1", `{"id":"w1","name":"Wendy","role":"witness"}`)
+	e.mustFail("AddParticipant", "C1", `{"id":"l1","name":"Bob Law","role":"counsel","barNumber":"NY1","barJurisdiction":"NY","represents":["w1"]}`)
+	e.mustInvoke("AddParticipant", "C1", `{"id":"l1","name":"Bob Law","role":"counsel","barNumber":"NY1","barJurisdiction":"NY","represents":["p1"],"enrollmentID":"bob"}`)
+
+	legalRecord := getStoredLegalRecord(e, "C1")
+	if len(legalRecord.Participants) != 3 || legalRecord.Participants[2].Role != ParticipantCounsel {
+		t.Fatalf("unexpected participants %+v", legalRecord.Participants)
+	}
+
+	// Only approvers of the owning organization change participants
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("AddParticipant", "C1", `{"id":"w2","name":"Walt","role":"witness"}`)
+	e.as("Org1MSP", "bob", "lawyer")
+	e.mustFail("AddParticipant", "C1", `{"id":"w2","name":"Walt","role":"witness"}`)
+}
+
+func TestRemoveParticipant(t *testing.T) {
+	e := newParticipantEnv(t)
+	e.mustInvoke("AddParticipant", "C1", `{"id":"l1","name":"Bob Law","role":"counsel","barNumber":"NY1","barJurisdiction":"NY","represents":["p1"],"enrollmentID":"bob"}`)
+
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("RemoveParticipant", "C1", "l1")
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("RemoveParticipant", "C1", "p1")
+	e.mustFail("RemoveParticipant", "C1", "p9")
+	e.mustInvoke("RemoveParticipant", "C1", "l1")
+	e.mustInvoke("RemoveParticipant", "C1", "p1")
+	if legalRecord := getStoredLegalRecord(e, "C1"); len(legalRecord.Participants) != 0 {
+		t.Fatalf("unexpected participants %+v", legalRecord.Participants)
+	}
+}
+
+func TestCounselOfRecordAccess(t *testing.T) {
+	e := newParticipantEnv(t)
+	e.as("Org1MSP", "bob", "lawyer")
+	if view := queryRecordView(e, "C1"); len(view.Participants) != 0 {
+		t.Fatalf("lawyer not of record sees %+v", view)
+	}
+
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("AddParticipant", "C1", `{"id":"l1","name":"Bob Law","role":"counsel","barNumber":"NY1","barJurisdiction":"NY","represents":["p1"],"enrollmentID":"bob"}`)
+	e.as("Org1MSP", "bob", "lawyer")
+	if view := queryRecordView(e, "C1"); len(view.Participants) != 2 {
+		t.Fatalf("counsel of record sees %+v", view)
+	}
+
+	// Access ends with the bar registration
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("UpdateLawyerStatus", "NY", "NY1", "disbarred", "misconduct")
+	e.as("Org1MSP", "bob", "lawyer")
+	if view := queryRecordView(e, "C1"); len(view.Participants) != 0 {
+		t.Fatalf("disbarred counsel sees %+v", view)
+	}
+}
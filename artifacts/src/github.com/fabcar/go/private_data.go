@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// legalRecordPrivateTransientKey is the transient map entry holding the private fields of a legal
+// record. Non-public records only accept their private fields there, so they never appear in the
+// transaction arguments.
+const legalRecordPrivateTransientKey = "legalRecordPrivate"
+
+// minPrivateDataSaltLength is the shortest salt accepted for the private details of a legal record
+const minPrivateDataSaltLength = 16
+
+// LegalRecordPrivateDetails holds the fields of a non-public legal record that are kept in a private
+// data collection. Only its SHA-256 hash is stored on the public record. The salt is chosen by the
+// client so that short or guessable descriptions cannot be recovered from the hash.
+type LegalRecordPrivateDetails struct {
+	CaseID      string `json:"caseID"`
+	Description string `json:"description"`
+	Proceedings string `json:"proceedings"`
+	Salt        string `json:"salt"`
+}
+
+// privateCollectionName selects the collection (see collections_config.json) for a confidentiality
+// level and owning court organization. Public records are not stored in a collection. Every
+// organization that registers courts needs both of its collections in the collection config.
+func privateCollectionName(confidentiality string, ownerMSP string) string {
+	if len(ownerMSP) == 0 || strings.EqualFold(confidentiality, ConfidentialityPublic) {
+		return ""
+	}
+	if strings.EqualFold(confidentiality, ConfidentialitySealed) {
+		return "sealedRecords" + ownerMSP
+	}
+	return "confidentialRecords" + ownerMSP
+}
+
+// getTransientPrivateDetails reads the private fields passed in the transient map over details, so
+// fields missing from the transient data keep their value. It returns false when none were passed.
+func getTransientPrivateDetails(ctx contractapi.TransactionContextInterface, details *LegalRecordPrivateDetails) (bool, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return false, fmt.Errorf("Failed to get transient data. %s", err.Error())
+	}
+	detailsAsBytes, ok := transientMap[legalRecordPrivateTransientKey]
+	if !ok {
+		return false, nil
+	}
+
+	err = json.Unmarshal(detailsAsBytes, details)
+	if err != nil {
+		return false, fmt.Errorf("Failed to unmarshal private legal record details. %s", err.Error())
+	}
+	return true, nil
+}
+
+// requireNoPublicPrivateFields rejects description and proceedings passed in the arguments of a
+// non-public record, whose private fields must come through the transient map
+func requireNoPublicPrivateFields(legalRecord *LegalRecord, description string, proceedings string) error {
+	if len(legalRecord.PrivateCollection) > 0 && (len(description) > 0 || len(proceedings) > 0) {
+		return fmt.Errorf("Description and proceedings of %s must be passed in the transient field %s", legalRecord.CaseID, legalRecordPrivateTransientKey)
+	}
+	return nil
+}
+
+// putLegalRecordPrivateDetails writes the private fields to the record's collection and stores
+// their hash on the public record. The caller still has to put the public record.
+func putLegalRecordPrivateDetails(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord, details *LegalRecordPrivateDetails) error {
+	if len(details.Salt) < minPrivateDataSaltLength {
+		return fmt.Errorf("The private details of %s need a salt of at least %d characters", legalRecord.CaseID, minPrivateDataSaltLength)
+	}
+
+	details.CaseID = legalRecord.CaseID
+	detailsAsBytes, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal private legal record details. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutPrivateData(legalRecord.PrivateCollection, legalRecord.CaseID, detailsAsBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to put private legal record details. %s", err.Error())
+	}
+
+	hash := sha256.Sum256(detailsAsBytes)
+	legalRecord.PrivateDataHash = hex.EncodeToString(hash[:])
+	legalRecord.Description = ""
+	legalRecord.Proceedings = ""
+	return nil
+}
+
+// moveLegalRecordPrivateDetails writes the private fields of a legal record to the collection of its
+// current owner when they are kept elsewhere. The fields come from the transient map, since peers of
+// the new owner cannot read the old collection, and must match the hash already on the record. The
+// old collection keeps its copy, as only its members may write to it.
+func moveLegalRecordPrivateDetails(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) error {
+	collection := privateCollectionName(legalRecord.Confidentiality, legalRecord.OwnerMSP)
+	if collection == legalRecord.PrivateCollection {
+		return nil
+	}
+
+	details := new(LegalRecordPrivateDetails)
+	passed, err := getTransientPrivateDetails(ctx, details)
+	if err != nil {
+		return err
+	}
+	if !passed {
+		return fmt.Errorf("Private details of %s must be passed in the transient field %s to move them to %s", legalRecord.CaseID, legalRecordPrivateTransientKey, collection)
+	}
+
+	previousHash := legalRecord.PrivateDataHash
+	hadCollection := len(legalRecord.PrivateCollection) > 0
+	legalRecord.PrivateCollection = collection
+	err = putLegalRecordPrivateDetails(ctx, legalRecord, details)
+	if err != nil {
+		return err
+	}
+	if hadCollection && legalRecord.PrivateDataHash != previousHash {
+		return fmt.Errorf("Private details passed for %s do not match the hash on the legal record", legalRecord.CaseID)
+	}
+	return nil
+}
+
+// getLegalRecordPrivateDetails reads the private fields of a legal record and verifies them against
+// the hash on the public record. It returns nil when the data is not available to this peer or client.
+func getLegalRecordPrivateDetails(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) (*LegalRecordPrivateDetails, error) {
+	if len(legalRecord.PrivateCollection) == 0 {
+		return nil, nil
+	}
+
+	detailsAsBytes, err := ctx.GetStub().GetPrivateData(legalRecord.PrivateCollection, legalRecord.CaseID)
+	if err != nil {
+		logger.Debugf("Private details of %s not readable from %s: %s", legalRecord.CaseID, legalRecord.PrivateCollection, err.Error())
+		return nil, nil
+	}
+	if detailsAsBytes == nil {
+		return nil, nil
+	}
+
+	hash := sha256.Sum256(detailsAsBytes)
+	if hex.EncodeToString(hash[:]) != legalRecord.PrivateDataHash {
+		return nil, fmt.Errorf("Private data of %s does not match the hash on the legal record", legalRecord.CaseID)
+	}
+
+	details := new(LegalRecordPrivateDetails)
+	err = json.Unmarshal(detailsAsBytes, details)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal private legal record details. %s", err.Error())
+	}
+	return details, nil
+}
+
+// withPrivateDetails returns a copy of the legal record with its private fields filled in when they
+// are available. The returned record must never be written back to the world state.
+func withPrivateDetails(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) (*LegalRecord, error) {
+	details, err := getLegalRecordPrivateDetails(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	full := *legalRecord
+	if details != nil {
+		full.Description = details.Description
+		full.Proceedings = details.Proceedings
+	}
+	return &full, nil
+}
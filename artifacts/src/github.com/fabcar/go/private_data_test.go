@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPrivateCollectionName(t *testing.T) {
+	for _, c := range []struct {
+		confidentiality string
+		ownerMSP        string
+		collection      string
+	}{
+		{"PUBLIC", "Org1MSP", ""},
+		{"public", "Org1MSP", ""},
+		{"SEALED", "Org1MSP", "sealedRecordsOrg1MSP"},
+		{"sealed", "Org2MSP", "sealedRecordsOrg2MSP"},
+		{"CONFIDENTIAL", "Org1MSP", "confidentialRecordsOrg1MSP"},
+		{"", "Org1MSP", "confidentialRecordsOrg1MSP"},
+		{"SEALED", "", ""},
+	} {
+		if collection := privateCollectionName(c.confidentiality, c.ownerMSP); collection != c.collection {
+			t.Errorf("privateCollectionName(%q, %q) = %q, want %q", c.confidentiality, c.ownerMSP, collection, c.collection)
+		}
+	}
+}
+
+func TestCreatePrivateLegalRecord(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	sealed := map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}, "confidentiality": "SEALED", "usersWithAccess": []string{"admin1"}}
+
+	// Private fields are only taken from the transient map, with a salt
+	e.mustFail("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}, "confidentiality": "SEALED", "description": "leaked"}))
+	e.transient = nil
+	e.mustFail("CreateLegalRecord", toJSON(t, sealed))
+	e.setPrivateDetails(map[string]string{"description": "Sealed matter", "salt": "short"})
+	e.mustFail("CreateLegalRecord", toJSON(t, sealed))
+
+	e.setPrivateDetails(map[string]string{"description": "Sealed matter", "proceedings": "/sealed/c1.pdf"})
+	e.mustInvoke("CreateLegalRecord", toJSON(t, sealed))
+
+	legalRecord := getStoredLegalRecord(e, "C1")
+	if legalRecord.PrivateCollection != "sealedRecordsOrg1MSP" || len(legalRecord.Description) > 0 || len(legalRecord.Proceedings) > 0 {
+		t.Fatalf("private fields stored on the public record %+v", legalRecord)
+	}
+	detailsAsBytes := e.stub.PvtState["sealedRecordsOrg1MSP"]["C1"]
+	if !strings.Contains(string(detailsAsBytes), "/sealed/c1.pdf") {
+		t.Fatalf("unexpected private details %s", detailsAsBytes)
+	}
+	hash := sha256.Sum256(detailsAsBytes)
+	if legalRecord.PrivateDataHash != hex.EncodeToString(hash[:]) {
+		t.Fatalf("hash %s does not match the private details", legalRecord.PrivateDataHash)
+	}
+	if jsonField(t, e.mustInvoke("QueryLegalRecord", "C1"), "proceedings") != "/sealed/c1.pdf" {
+		t.Fatal("private details not returned to the owning approver")
+	}
+
+	// The same details under another salt hash differently
+	e.setPrivateDetails(map[string]string{"description": "Sealed matter", "proceedings": "/sealed/c1.pdf", "salt": strings.Repeat("x", minPrivateDataSaltLength)})
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C2", "courtID": "CT1", "judges": []string{}, "confidentiality": "SEALED", "usersWithAccess": []string{}}))
+	if other := getStoredLegalRecord(e, "C2"); other.PrivateDataHash == legalRecord.PrivateDataHash {
+		t.Fatal("salt does not change the private data hash")
+	}
+
+	// Public records keep their fields on the record
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C3", "courtID": "CT1", "judges": []string{}, "confidentiality": "PUBLIC", "description": "Open matter"}))
+	if public := getStoredLegalRecord(e, "C3"); public.PrivateCollection != "" || public.Description != "Open matter" {
+		t.Fatalf("unexpected public record %+v", public)
+	}
+}
+
+func TestUpdatePrivateLegalRecord(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.setPrivateDetails(map[string]string{"description": "Confidential matter", "proceedings": "/conf/c1.pdf"})
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}, "confidentiality": "CONFIDENTIAL", "usersWithAccess": []string{"admin1"}}))
+	before := getStoredLegalRecord(e, "C1").PrivateDataHash
+
+	e.mustFail("UpdateLegalRecord", "C1", `{"proceedings":"/leaked.pdf"}`)
+
+	// Fields missing from the transient map keep their value
+	e.setPrivateDetails(map[string]string{"proceedings": "/conf/c1-v2.pdf"})
+	e.mustInvoke("UpdateLegalRecord", "C1", `{"status":"OPEN"}`)
+	legalRecord := e.mustInvoke("QueryLegalRecord", "C1")
+	if jsonField(t, legalRecord, "proceedings") != "/conf/c1-v2.pdf" || jsonField(t, legalRecord, "description") != "Confidential matter" {
+		t.Fatalf("unexpected private details %s", legalRecord)
+	}
+	if stored := getStoredLegalRecord(e, "C1"); stored.PrivateDataHash == before || stored.PrivateCollection != "confidentialRecordsOrg1MSP" {
+		t.Fatalf("unexpected stored record %+v", stored)
+	}
+
+	// Private data that no longer matches the hash on the record is not returned
+	e.stub.PvtState["confidentialRecordsOrg1MSP"]["C1"] = []byte(`{"caseID":"C1","description":"forged","proceedings":"","salt":"` + testSalt + `"}`)
+	if msg := e.mustFail("QueryLegalRecord", "C1"); !strings.Contains(msg, "does not match the hash") {
+		t.Fatal(msg)
+	}
+}
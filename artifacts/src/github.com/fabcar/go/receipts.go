@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	ReceiptLegalRecord = "LEGAL_RECORD"
+	ReceiptUser        = "USER"
+	ReceiptFiling      = "FILING"
+)
+
+// chaincodeVersion is the version the chaincode is deployed with (VERSION in deployChaincode.sh). It
+// is compiled in rather than read from the peer, so every endorsing peer writes the same receipt.
+const chaincodeVersion = "1"
+
+// Receipt is returned by transactions that write a record, as proof of what was stored and when.
+// RecordHash is the hex encoded SHA-256 of the stored value. RecordID is the case ID of legal records,
+// the user ID of users and the filing ID of filings, whose case is given in CaseID. Receipts of legal
+// records hash only the public record in the world state: the details held in a private collection
+// are covered through the record's PrivateDataHash, not hashed themselves.
+type Receipt struct {
+	TxID             string `json:"txID"`
+	TxTimestamp      string `json:"txTimestamp"`
+	Channel          string `json:"channel"`
+	ChaincodeVersion string `json:"chaincodeVersion"`
+	RecordType       string `json:"recordType"`
+	RecordID         string `json:"recordID"`
+	CaseID           string `json:"caseID,omitempty" metadata:"caseID,optional"`
+	RecordHash       string `json:"recordHash"`
+}
+
+// VerifyReceipt checks a receipt against the history of its record. It returns true when the receipt's
+// transaction wrote the record on this channel at the receipt's timestamp and the value it wrote has
+// the receipt's hash, and false when the record was deleted by that transaction, the value is not a
+// record of the receipt's type or the hash differs. It reads the history database, so peers must run
+// with core.ledger.history.enableHistoryDatabase.
+func (s *SmartContract) VerifyReceipt(ctx contractapi.TransactionContextInterface, receiptJSON string) (bool, error) {
+	var receipt Receipt
+	err := json.Unmarshal([]byte(receiptJSON), &receipt)
+	if err != nil {
+		return false, fmt.Errorf("Failed while unmarshalling receipt. %s", err.Error())
+	}
+	if len(receipt.TxID) == 0 || len(receipt.RecordID) == 0 || len(receipt.RecordHash) == 0 {
+		return false, fmt.Errorf("Receipt transaction, record and hash are required")
+	}
+	if receipt.Channel != ctx.GetStub().GetChannelID() {
+		return false, fmt.Errorf("Receipt was issued on channel %s", receipt.Channel)
+	}
+
+	recordKey, err := getReceiptRecordKey(ctx, &receipt)
+	if err != nil {
+		return false, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(recordKey)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get history iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return false, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+		if modification.TxId != receipt.TxID {
+			continue
+		}
+
+		if modification.IsDelete {
+			return false, nil
+		}
+		if modification.Timestamp == nil {
+			return false, fmt.Errorf("History of %s %s has no timestamp for transaction %s", receipt.RecordType, receipt.RecordID, receipt.TxID)
+		}
+		timestamp := time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC().Format(time.RFC3339)
+		if timestamp != receipt.TxTimestamp {
+			return false, nil
+		}
+		if !isReceiptRecord(&receipt, modification.Value) {
+			return false, nil
+		}
+		return hashRecord(modification.Value) == receipt.RecordHash, nil
+	}
+
+	return false, fmt.Errorf("Transaction %s did not write %s %s", receipt.TxID, receipt.RecordType, receipt.RecordID)
+}
+
+// newReceipt builds the receipt of the current transaction for a stored record value
+func newReceipt(ctx contractapi.TransactionContextInterface, recordType string, recordID string, caseID string, recordAsBytes []byte) (*Receipt, error) {
+	txTimestamp, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Receipt{
+		TxID:             ctx.GetStub().GetTxID(),
+		TxTimestamp:      txTimestamp,
+		Channel:          ctx.GetStub().GetChannelID(),
+		ChaincodeVersion: chaincodeVersion,
+		RecordType:       recordType,
+		RecordID:         recordID,
+		CaseID:           caseID,
+		RecordHash:       hashRecord(recordAsBytes),
+	}, nil
+}
+
+func hashRecord(recordAsBytes []byte) string {
+	hash := sha256.Sum256(recordAsBytes)
+	return hex.EncodeToString(hash[:])
+}
+
+// isReceiptRecord reports whether a stored value is a record of the receipt's type and ID. Legal
+// records and users share the simple key space, so the key alone does not tell them apart.
+func isReceiptRecord(receipt *Receipt, recordAsBytes []byte) bool {
+	var ids struct {
+		ID       string `json:"id"`
+		CaseID   string `json:"caseID"`
+		FilingID string `json:"filingID"`
+	}
+	err := json.Unmarshal(recordAsBytes, &ids)
+	if err != nil {
+		return false
+	}
+	switch receipt.RecordType {
+	case ReceiptLegalRecord:
+		return ids.CaseID == receipt.RecordID && len(ids.ID) == 0
+	case ReceiptUser:
+		return ids.ID == receipt.RecordID && len(ids.CaseID) == 0
+	case ReceiptFiling:
+		return ids.FilingID == receipt.RecordID && ids.CaseID == receipt.CaseID
+	default:
+		return false
+	}
+}
+
+// getReceiptRecordKey returns the world state key of the record a receipt was issued for
+func getReceiptRecordKey(ctx contractapi.TransactionContextInterface, receipt *Receipt) (string, error) {
+	switch receipt.RecordType {
+	case ReceiptLegalRecord, ReceiptUser:
+		return receipt.RecordID, nil
+	case ReceiptFiling:
+		filingKey, err := ctx.GetStub().CreateCompositeKey(filingObjectType, []string{receipt.CaseID, receipt.RecordID})
+		if err != nil {
+			return "", fmt.Errorf("Failed to create composite key. %s", err.Error())
+		}
+		return filingKey, nil
+	default:
+		return "", fmt.Errorf("Invalid receipt record type: %s", receipt.RecordType)
+	}
+}
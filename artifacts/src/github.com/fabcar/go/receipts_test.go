@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// historyStub serves key histories to VerifyReceipt, as the mock stub does not keep them
+type historyStub struct {
+	*shimtest.MockStub
+	history map[string][]*queryresult.KeyModification
+}
+
+func (s *historyStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{modifications: s.history[key]}, nil
+}
+
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+	next          int
+}
+
+func (it *historyIterator) HasNext() bool {
+	return it.next < len(it.modifications)
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	it.next++
+	return it.modifications[it.next-1], nil
+}
+
+func (it *historyIterator) Close() error {
+	return nil
+}
+
+func TestLegalRecordReceipts(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	created := e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}}))
+	if jsonField(t, created, "recordHash") != hashRecord(e.stub.State["C1"]) {
+		t.Fatalf("receipt %s does not hash the stored record", created)
+	}
+	updated := e.mustInvoke("UpdateLegalRecord", "C1", `{"status":"OPEN"}`)
+	if jsonField(t, updated, "recordHash") != hashRecord(e.stub.State["C1"]) || jsonField(t, updated, "txID") != fmt.Sprintf("tx%04d", e.txCount) {
+		t.Fatalf("receipt %s does not match the update", updated)
+	}
+	if jsonField(t, created, "recordType") != ReceiptLegalRecord || jsonField(t, created, "recordID") != "C1" {
+		t.Fatal(created)
+	}
+	// Every peer reports the compiled-in version, whatever its environment
+	os.Setenv("CORE_CHAINCODE_ID_NAME", "fabcar_1:peer-specific")
+	defer os.Unsetenv("CORE_CHAINCODE_ID_NAME")
+	if version := jsonField(t, e.mustInvoke("UpdateLegalRecord", "C1", `{"status":"CLOSED"}`), "chaincodeVersion"); version != chaincodeVersion || version != jsonField(t, created, "chaincodeVersion") {
+		t.Fatalf("receipt reports chaincode version %q", version)
+	}
+
+	user := e.mustInvoke("CreateUser", `{"id":"u1","name":"User One"}`)
+	if jsonField(t, user, "recordType") != ReceiptUser || jsonField(t, user, "recordHash") != hashRecord(e.stub.State["u1"]) {
+		t.Fatal(user)
+	}
+}
+
+func TestVerifyReceipt(t *testing.T) {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	created := e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{}}))
+	createdValue := e.stub.State["C1"]
+	updated := e.mustInvoke("UpdateLegalRecord", "C1", `{"status":"OPEN"}`)
+	updatedValue := e.stub.State["C1"]
+
+	txTimestamp, err := time.Parse(time.RFC3339, jsonField(t, created, "txTimestamp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writtenAt := &timestamp.Timestamp{Seconds: txTimestamp.Unix()}
+	stub := &historyStub{MockStub: e.stub, history: map[string][]*queryresult.KeyModification{
+		"C1": {
+			{TxId: jsonField(t, updated, "txID"), Value: updatedValue, Timestamp: writtenAt},
+			{TxId: jsonField(t, created, "txID"), Value: createdValue, Timestamp: writtenAt},
+			{TxId: "untimed", Value: createdValue},
+			{TxId: "deleted", IsDelete: true, Timestamp: writtenAt},
+		},
+	}}
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+
+	withTxID := func(txID string) string {
+		return strings.Replace(created, jsonField(t, created, "txID"), txID, 1)
+	}
+	tests := []struct {
+		name     string
+		receipt  string
+		verified bool
+		fails    bool
+	}{
+		{"created", created, true, false},
+		{"updated", updated, true, false},
+		{"hash of another version", strings.Replace(created, jsonField(t, created, "recordHash"), hashRecord(updatedValue), 1), false, false},
+		{"other timestamp", strings.Replace(created, `"txTimestamp":"`, `"txTimestamp":"1`, 1), false, false},
+		{"user receipt for a legal record", strings.Replace(created, ReceiptLegalRecord, ReceiptUser, 1), false, false},
+		{"deleted", withTxID("deleted"), false, false},
+		{"no timestamp in history", withTxID("untimed"), false, true},
+		{"transaction did not write the record", withTxID("unknown"), false, true},
+		{"other channel", strings.Replace(created, `"channel":"`, `"channel":"other`, 1), false, true},
+		{"invalid record type", strings.Replace(created, ReceiptLegalRecord, "CAR", 1), false, true},
+		{"missing hash", strings.Replace(created, jsonField(t, created, "recordHash"), "", 1), false, true},
+	}
+	for _, test := range tests {
+		verified, err := new(SmartContract).VerifyReceipt(ctx, test.receipt)
+		if verified != test.verified || (err != nil) != test.fails {
+			t.Errorf("%s: got %v, %v", test.name, verified, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	ConfidentialityPublic = "PUBLIC"
+	ConfidentialitySealed = "SEALED"
+)
+
+// getClientRole returns the role attribute of the client identity, or an empty string when it has none
+func getClientRole(ctx contractapi.TransactionContextInterface) (string, error) {
+	value, _, err := cid.GetAttributeValue(ctx.GetStub(), "role")
+	if err != nil {
+		return "", fmt.Errorf("failed while getting attribute. %s", err.Error())
+	}
+	return value, nil
+}
+
+// Views of a legal record, from the most to the least detailed
+const (
+	RecordViewFull    = "FULL"
+	RecordViewCounsel = "COUNSEL"
+	RecordViewSummary = "SUMMARY"
+)
+
+// getRecordView returns the view of a legal record the calling identity may see:
+//
+//   - FULL: public records, judges assigned to the case and approvers of the owning organization
+//   - COUNSEL: lawyers of record and users granted access, who see everything except the
+//     proceedings of sealed cases
+//   - SUMMARY: everyone else, who see the case type, court and status only
+func getRecordView(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) (string, error) {
+	if strings.EqualFold(legalRecord.Confidentiality, ConfidentialityPublic) {
+		return RecordViewFull, nil
+	}
+	custodian, err := isRecordCustodian(ctx, legalRecord)
+	if err != nil {
+		return "", err
+	}
+	if custodian {
+		return RecordViewFull, nil
+	}
+
+	role, err := getClientRole(ctx)
+	if err != nil {
+		return "", err
+	}
+	username, err := getClientName(ctx)
+	if err != nil {
+		return "", err
+	}
+	if role == "lawyer" && isCounselOfRecord(ctx, legalRecord, username) {
+		return RecordViewCounsel, nil
+	}
+	for _, user := range legalRecord.UsersWithAccess {
+		if strings.EqualFold(user, username) {
+			return RecordViewCounsel, nil
+		}
+	}
+	return RecordViewSummary, nil
+}
+
+// isRecordCustodian reports whether the caller is a judge assigned to the case or an approver of
+// the organization owning it
+func isRecordCustodian(ctx contractapi.TransactionContextInterface, legalRecord *LegalRecord) (bool, error) {
+	role, err := getClientRole(ctx)
+	if err != nil {
+		return false, err
+	}
+	switch role {
+	case "judge":
+		_, err = getAssignedJudgeForClient(ctx, legalRecord)
+		return err == nil, nil
+	case "approver":
+		return len(legalRecord.OwnerMSP) == 0 || requireCourtOwner(ctx, legalRecord.OwnerMSP) == nil, nil
+	}
+	return false, nil
+}
+
+// redactLegalRecord returns the given view of a legal record. The stored record is never modified.
+func redactLegalRecord(legalRecord *LegalRecord, recordView string) *LegalRecord {
+	view := *legalRecord
+	if view.Judges == nil {
+		view.Judges = []string{}
+	}
+	if view.UsersWithAccess == nil {
+		view.UsersWithAccess = []string{}
+	}
+
+	switch recordView {
+	case RecordViewFull:
+		return &view
+	case RecordViewCounsel:
+		if strings.EqualFold(legalRecord.Confidentiality, ConfidentialitySealed) {
+			view.Proceedings = ""
+		}
+		return &view
+	}
+
+	return &LegalRecord{
+		CaseID:           legalRecord.CaseID,
+		CaseType:         legalRecord.CaseType,
+		CourtType:        legalRecord.CourtType,
+		CourtCategory:    legalRecord.CourtCategory,
+		CourtZip:         legalRecord.CourtZip,
+		Confidentiality:  legalRecord.Confidentiality,
+		Status:           legalRecord.Status,
+		ConsolidatedInto: legalRecord.ConsolidatedInto,
+		Judges:           []string{},
+		UsersWithAccess:  []string{},
+	}
+}
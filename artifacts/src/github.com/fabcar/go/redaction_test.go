@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newRedactionEnv returns an environment with the sealed case C1 of CT1 assigned to judge j, with
+// lawyer bob as counsel of record and alice granted access, and judge k of court CT3 of the same
+// organization
+func newRedactionEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "bar1", "barAssociation")
+	e.mustInvoke("RegisterLawyer", `{"barNumber":"NY1","jurisdiction":"NY","name":"Bob Law"}`)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("CreateCourt", `{"id":"CT3","name":"District 3","type":"district","category":"civil","zipCodes":["10003"]}`)
+	e.mustInvoke("CreateJudge", `{"id":"k","name":"Kim","courtID":"CT3","active":true,"enrollmentID":"k"}`)
+	e.setPrivateDetails(map[string]string{"proceedings": "Closed hearing"})
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{
+		"caseID":          "C1",
+		"courtID":         "CT1",
+		"judges":          []string{"j"},
+		"confidentiality": "SEALED",
+		"usersWithAccess": []string{"alice"},
+		"participants": []map[string]interface{}{
+			{"id": "p1", "name": "Widget Co", "role": "defendant"},
+			{"id": "l1", "name": "Bob Law", "role": "counsel", "barNumber": "NY1", "barJurisdiction": "NY", "represents": []string{"p1"}, "enrollmentID": "bob"},
+		},
+	}))
+	return e
+}
+
+func queryRecordView(e *testEnv, caseID string) *LegalRecord {
+	e.t.Helper()
+	legalRecord := new(LegalRecord)
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryLegalRecord", caseID)), legalRecord)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return legalRecord
+}
+
+func TestQueryLegalRecordViews(t *testing.T) {
+	e := newRedactionEnv(t)
+
+	tests := []struct {
+		name        string
+		mspID       string
+		username    string
+		role        string
+		proceedings bool
+		summary     bool
+	}{
+		{"assigned judge", "Org1MSP", "j", "judge", true, false},
+		{"approver of the owning organization", "Org1MSP", "admin1", "approver", true, false},
+		{"counsel of record", "Org1MSP", "bob", "lawyer", false, false},
+		{"user granted access", "Org2MSP", "alice", "client", false, false},
+		{"judge of another court", "Org1MSP", "k", "judge", false, true},
+		{"judge not bound to the assigned judge", "Org2MSP", "j", "judge", false, true},
+		{"approver of another organization", "Org2MSP", "admin2", "approver", false, true},
+		{"lawyer not of record", "Org1MSP", "eve", "lawyer", false, true},
+		{"counsel identity without the lawyer role", "Org1MSP", "bob", "client", false, true},
+		{"client", "Org1MSP", "mallory", "client", false, true},
+	}
+	for _, test := range tests {
+		e.as(test.mspID, test.username, test.role)
+		view := queryRecordView(e, "C1")
+		if (view.Proceedings == "Closed hearing") != test.proceedings {
+			t.Errorf("%s: proceedings %q", test.name, view.Proceedings)
+		}
+		if (len(view.Participants) == 0) != test.summary || len(view.Judges) == 0 && !test.summary {
+			t.Errorf("%s: got %+v", test.name, view)
+		}
+		if view.CourtZip != "10001" || view.Confidentiality != "SEALED" {
+			t.Errorf("%s: summary fields missing from %+v", test.name, view)
+		}
+	}
+}
+
+func TestCaseReaders(t *testing.T) {
+	e := newRedactionEnv(t)
+	e.mustInvoke("AddDocketEntry", "C1", `{"type":"minute","description":"Sealed conference","sealed":true}`)
+	e.mustInvoke("AddDocketEntry", "C1", `{"type":"minute","description":"Status conference"}`)
+
+	getDocket := func() *DocketPage {
+		t.Helper()
+		docket := new(DocketPage)
+		err := json.Unmarshal([]byte(e.mustInvoke("GetDocket", "C1", "0", "")), docket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return docket
+	}
+
+	e.as("Org1MSP", "j", "judge")
+	if docket := getDocket(); len(docket.Entries) != 2 {
+		t.Fatalf("assigned judge sees %+v", docket.Entries)
+	}
+	e.as("Org1MSP", "bob", "lawyer")
+	if docket := getDocket(); len(docket.Entries) != 1 || docket.Entries[0].Sealed {
+		t.Fatalf("counsel sees %+v", docket.Entries)
+	}
+
+	// Judges of other courts and approvers of other organizations no longer read the case
+	e.as("Org1MSP", "k", "judge")
+	e.mustFail("GetDocket", "C1", "0", "")
+	e.mustFail("ReadLegalRecordAudited", "C1", "review")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("GetDocket", "C1", "0", "")
+	e.mustFail("ReadLegalRecordAudited", "C1", "review")
+
+	// The audited read is logged with the view it returned
+	e.as("Org1MSP", "bob", "lawyer")
+	if record := e.mustInvoke("ReadLegalRecordAudited", "C1", "motion"); jsonField(t, record, "proceedings") != "" {
+		t.Fatal(record)
+	}
+	e.as("Org1MSP", "admin1", "approver")
+	entries := getRecordAccessLog(e, "C1")
+	if len(entries) != 1 || entries[0].Reader != "bob" || entries[0].View != RecordViewCounsel {
+		t.Fatalf("unexpected access log %+v", entries)
+	}
+}
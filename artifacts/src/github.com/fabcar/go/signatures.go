@@ -0,0 +1,482 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	mspTrustAnchorsObjectType         = "mspTrustAnchors"
+	mspTrustAnchorsProposalObjectType = "mspTrustAnchorsProposal"
+	orderSignatureObjectType          = "orderSignature"
+)
+
+// MSPTrustAnchors holds the PEM encoded root and intermediate CA certificates of a channel MSP and
+// the CRLs issued by them. Chaincode cannot read the channel configuration, so each organization
+// proposes its own CAs and CRLs and they only take effect once an approver of another organization
+// has checked them against the channel configuration and approved them.
+type MSPTrustAnchors struct {
+	MSPID             string   `json:"mspID"`
+	RootCerts         []string `json:"rootCerts"`
+	IntermediateCerts []string `json:"intermediateCerts,omitempty" metadata:"intermediateCerts,optional"`
+	CRLs              []string `json:"crls,omitempty" metadata:"crls,optional"`
+	UpdatedBy         string   `json:"updatedBy"`
+	UpdatedAt         string   `json:"updatedAt"`
+	ApprovedBy        string   `json:"approvedBy,omitempty" metadata:"approvedBy,optional"`
+	ApprovedMSP       string   `json:"approvedMSP,omitempty" metadata:"approvedMSP,optional"`
+	ApprovedAt        string   `json:"approvedAt,omitempty" metadata:"approvedAt,optional"`
+}
+
+// OrderSignature is a judge's detached ECDSA signature over the text hash of an order, stored once
+// it has been verified against the judge's certificate and MSP.
+type OrderSignature struct {
+	CaseID      string `json:"caseID"`
+	OrderID     string `json:"orderID"`
+	JudgeID     string `json:"judgeID"`
+	MSPID       string `json:"mspID"`
+	Hash        string `json:"hash"`
+	Signature   string `json:"signature"`
+	Certificate string `json:"certificate"`
+	SubmittedBy string `json:"submittedBy"`
+	VerifiedAt  string `json:"verifiedAt"`
+	TxID        string `json:"txID"`
+}
+
+// ProposeMSPTrustAnchors proposes new CA certificates and CRLs for the caller's organization, used
+// to verify judge signatures. trustAnchorsJSON holds rootCerts and optional intermediateCerts and
+// crls in PEM. The proposal replaces any earlier one and takes effect when another organization
+// approves it with ApproveMSPTrustAnchors.
+func (s *SmartContract) ProposeMSPTrustAnchors(ctx contractapi.TransactionContextInterface, trustAnchorsJSON string) error {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return err
+	}
+	mspID, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("Failed to get client MSP ID. %s", err.Error())
+	}
+
+	var anchors MSPTrustAnchors
+	err = json.Unmarshal([]byte(trustAnchorsJSON), &anchors)
+	if err != nil {
+		return fmt.Errorf("Failed while unmarshalling trust anchors. %s", err.Error())
+	}
+	if len(anchors.RootCerts) == 0 {
+		return fmt.Errorf("At least one root certificate is required")
+	}
+	var caCerts []*x509.Certificate
+	for _, certPEM := range append(append([]string{}, anchors.RootCerts...), anchors.IntermediateCerts...) {
+		cert, err := parseCertificatePEM(certPEM)
+		if err != nil {
+			return err
+		}
+		if !cert.IsCA {
+			return fmt.Errorf("Certificate %s is not a CA certificate", cert.Subject.CommonName)
+		}
+		caCerts = append(caCerts, cert)
+	}
+	for _, crlPEM := range anchors.CRLs {
+		_, err = parseCRLPEM(crlPEM, caCerts)
+		if err != nil {
+			return err
+		}
+	}
+
+	anchors.MSPID = mspID
+	anchors.UpdatedBy, err = getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	anchors.UpdatedAt, err = getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	anchors.ApprovedBy = ""
+	anchors.ApprovedMSP = ""
+	anchors.ApprovedAt = ""
+
+	proposalKey, err := ctx.GetStub().CreateCompositeKey(mspTrustAnchorsProposalObjectType, []string{mspID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	anchorsAsBytes, err := putMSPTrustAnchors(ctx, proposalKey, &anchors, mspID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("ProposeMSPTrustAnchors", anchorsAsBytes)
+}
+
+// ApproveMSPTrustAnchors makes the pending trust anchors of another organization effective. The
+// approver is expected to have compared them with the organization's MSP in the channel
+// configuration. Later changes must be endorsed by both the organization and the approving one.
+func (s *SmartContract) ApproveMSPTrustAnchors(ctx contractapi.TransactionContextInterface, mspID string) error {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return err
+	}
+	approverMSP, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("Failed to get client MSP ID. %s", err.Error())
+	}
+	if approverMSP == mspID {
+		return fmt.Errorf("Trust anchors of %s must be approved by another organization", mspID)
+	}
+
+	proposalKey, err := ctx.GetStub().CreateCompositeKey(mspTrustAnchorsProposalObjectType, []string{mspID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	anchors, err := readMSPTrustAnchors(ctx, proposalKey)
+	if err != nil {
+		return err
+	}
+	if anchors == nil {
+		return fmt.Errorf("No trust anchors have been proposed for %s", mspID)
+	}
+
+	anchors.ApprovedBy, err = getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	anchors.ApprovedAt, err = getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	anchors.ApprovedMSP = approverMSP
+
+	anchorsKey, err := ctx.GetStub().CreateCompositeKey(mspTrustAnchorsObjectType, []string{mspID})
+	if err != nil {
+		return fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	anchorsAsBytes, err := putMSPTrustAnchors(ctx, anchorsKey, anchors, mspID, approverMSP)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(proposalKey)
+	if err != nil {
+		return fmt.Errorf("Failed to delete trust anchors proposal. %s", err.Error())
+	}
+
+	logger.Infof("Trust anchors of %s approved by %s", mspID, approverMSP)
+	return ctx.GetStub().SetEvent("ApproveMSPTrustAnchors", anchorsAsBytes)
+}
+
+func (s *SmartContract) QueryMSPTrustAnchors(ctx contractapi.TransactionContextInterface, mspID string) (*MSPTrustAnchors, error) {
+	return getMSPTrustAnchors(ctx, mspID)
+}
+
+// SignJudgment attaches a judge's detached signature to an order. judgmentHash is the hex SHA-256
+// of the order text, signature the base64 ASN.1 DER ECDSA signature over it and certificate the
+// judge's PEM certificate. The signature is only stored if it verifies, the certificate chains to
+// the judge's MSP and belongs to the judge that issued the order, and that judge is still assigned.
+func (s *SmartContract) SignJudgment(ctx contractapi.TransactionContextInterface, caseID string, orderID string, judgmentHash string, signature string, certificate string) (*OrderSignature, error) {
+	_, err := requireRole(ctx, "approver", "judge")
+	if err != nil {
+		return nil, err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := getOrder(ctx, caseID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Signature != nil {
+		return nil, fmt.Errorf("Order %s has already been signed", orderID)
+	}
+	if !strings.EqualFold(judgmentHash, order.TextHash) {
+		return nil, fmt.Errorf("Judgment hash does not match the text hash of order %s", orderID)
+	}
+	if !isJudgeAssigned(legalRecord, order.JudgeID) {
+		return nil, fmt.Errorf("Judge %s is no longer assigned to %s", order.JudgeID, caseID)
+	}
+	judge, err := getJudge(ctx, order.JudgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	verifiedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txTime, err := time.Parse(time.RFC3339, verifiedAt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse transaction timestamp. %s", err.Error())
+	}
+
+	cert, err := parseCertificatePEM(certificate)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyCertificateChain(ctx, cert, judge.MSPID, txTime)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Subject.CommonName != judge.EnrollmentID {
+		return nil, fmt.Errorf("Certificate %s does not belong to judge %s", cert.Subject.CommonName, judge.ID)
+	}
+	err = verifyECDSASignature(cert, order.TextHash, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	submittedBy, err := getClientName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orderSignature := &OrderSignature{
+		CaseID:      caseID,
+		OrderID:     orderID,
+		JudgeID:     judge.ID,
+		MSPID:       judge.MSPID,
+		Hash:        order.TextHash,
+		Signature:   signature,
+		Certificate: certificate,
+		SubmittedBy: submittedBy,
+		VerifiedAt:  verifiedAt,
+		TxID:        ctx.GetStub().GetTxID(),
+	}
+
+	signatureKey, err := ctx.GetStub().CreateCompositeKey(orderSignatureObjectType, []string{caseID, orderID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	signatureAsBytes, err := json.Marshal(orderSignature)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal order signature. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(signatureKey, signatureAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put order signature. %s", err.Error())
+	}
+
+	ctx.GetStub().SetEvent("SignJudgment", signatureAsBytes)
+
+	return orderSignature, nil
+}
+
+// verifyCertificateChain checks that cert was valid at the transaction time and chains to the
+// published trust anchors of mspID
+func verifyCertificateChain(ctx contractapi.TransactionContextInterface, cert *x509.Certificate, mspID string, at time.Time) error {
+	anchors, err := getMSPTrustAnchors(ctx, mspID)
+	if err != nil {
+		return err
+	}
+
+	roots := x509.NewCertPool()
+	for _, certPEM := range anchors.RootCerts {
+		root, err := parseCertificatePEM(certPEM)
+		if err != nil {
+			return err
+		}
+		roots.AddCert(root)
+	}
+	intermediates := x509.NewCertPool()
+	for _, certPEM := range anchors.IntermediateCerts {
+		intermediate, err := parseCertificatePEM(certPEM)
+		if err != nil {
+			return err
+		}
+		intermediates.AddCert(intermediate)
+	}
+
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("Certificate does not chain to %s. %s", mspID, err.Error())
+	}
+
+	// Every certificate below the root must not have been revoked by its issuer
+	for _, chain := range chains {
+		for i := 0; i+1 < len(chain); i++ {
+			err = checkNotRevoked(anchors, chain[i], chain[i+1], at)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkNotRevoked fails when a CRL of the trust anchors signed by issuer revoked cert before at
+func checkNotRevoked(anchors *MSPTrustAnchors, cert *x509.Certificate, issuer *x509.Certificate, at time.Time) error {
+	for _, crlPEM := range anchors.CRLs {
+		crl, err := parseCRLPEM(crlPEM, nil)
+		if err != nil {
+			return err
+		}
+		if issuer.CheckCRLSignature(crl) != nil {
+			continue
+		}
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 && !revoked.RevocationTime.After(at) {
+				return fmt.Errorf("Certificate %s was revoked by %s on %s", cert.Subject.CommonName, issuer.Subject.CommonName, revoked.RevocationTime.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+	return nil
+}
+
+// verifyECDSASignature checks a base64 ASN.1 DER ECDSA signature over a hex encoded digest
+func verifyECDSASignature(cert *x509.Certificate, hashHex string, signature string) error {
+	publicKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("Certificate does not hold an ECDSA public key")
+	}
+	digest, err := hex.DecodeString(hashHex)
+	if err != nil {
+		return fmt.Errorf("Invalid hash. %s", err.Error())
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("Signature must be base64 encoded. %s", err.Error())
+	}
+
+	var ecdsaSignature struct {
+		R, S *big.Int
+	}
+	_, err = asn1.Unmarshal(signatureBytes, &ecdsaSignature)
+	if err != nil {
+		return fmt.Errorf("Signature must be ASN.1 DER encoded. %s", err.Error())
+	}
+	if ecdsaSignature.R == nil || ecdsaSignature.S == nil || !ecdsa.Verify(publicKey, digest, ecdsaSignature.R, ecdsaSignature.S) {
+		return fmt.Errorf("Signature verification failed")
+	}
+	return nil
+}
+
+func parseCertificatePEM(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("Expected a PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse certificate. %s", err.Error())
+	}
+	return cert, nil
+}
+
+// parseCRLPEM parses a PEM encoded CRL. When caCerts are given, one of them must have signed it.
+func parseCRLPEM(crlPEM string, caCerts []*x509.Certificate) (*pkix.CertificateList, error) {
+	block, _ := pem.Decode([]byte(crlPEM))
+	if block == nil || block.Type != "X509 CRL" {
+		return nil, fmt.Errorf("Expected a PEM encoded CRL")
+	}
+	crl, err := x509.ParseDERCRL(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse CRL. %s", err.Error())
+	}
+	if caCerts == nil {
+		return crl, nil
+	}
+	for _, caCert := range caCerts {
+		if caCert.CheckCRLSignature(crl) == nil {
+			return crl, nil
+		}
+	}
+	return nil, fmt.Errorf("CRL %s is not signed by any of the CA certificates", crl.TBSCertList.Issuer.String())
+}
+
+// getMSPTrustAnchors returns the approved trust anchors of an organization
+func getMSPTrustAnchors(ctx contractapi.TransactionContextInterface, mspID string) (*MSPTrustAnchors, error) {
+	if len(mspID) == 0 {
+		return nil, fmt.Errorf("Please pass the correct MSP ID")
+	}
+
+	anchorsKey, err := ctx.GetStub().CreateCompositeKey(mspTrustAnchorsObjectType, []string{mspID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	anchors, err := readMSPTrustAnchors(ctx, anchorsKey)
+	if err != nil {
+		return nil, err
+	}
+	if anchors == nil {
+		return nil, fmt.Errorf("No trust anchors have been approved for %s", mspID)
+	}
+	return anchors, nil
+}
+
+func readMSPTrustAnchors(ctx contractapi.TransactionContextInterface, key string) (*MSPTrustAnchors, error) {
+	anchorsAsBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if anchorsAsBytes == nil {
+		return nil, nil
+	}
+
+	anchors := new(MSPTrustAnchors)
+	err = json.Unmarshal(anchorsAsBytes, anchors)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal trust anchors. %s", err.Error())
+	}
+	return anchors, nil
+}
+
+// putMSPTrustAnchors stores trust anchors under key so that only the given organizations together
+// may endorse later changes to them
+func putMSPTrustAnchors(ctx contractapi.TransactionContextInterface, key string, anchors *MSPTrustAnchors, endorsingMSPs ...string) ([]byte, error) {
+	anchorsAsBytes, err := json.Marshal(anchors)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal trust anchors. %s", err.Error())
+	}
+	err = ctx.GetStub().PutState(key, anchorsAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put trust anchors. %s", err.Error())
+	}
+
+	policy, err := endorsementPolicyForOrgs(endorsingMSPs...)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().SetStateValidationParameter(key, policy)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to set endorsement policy for trust anchors of %s. %s", anchors.MSPID, err.Error())
+	}
+	return anchorsAsBytes, nil
+}
+
+// getOrderSignature returns the verified signature of an order, or nil if it has not been signed
+func getOrderSignature(ctx contractapi.TransactionContextInterface, caseID string, orderID string) (*OrderSignature, error) {
+	signatureKey, err := ctx.GetStub().CreateCompositeKey(orderSignatureObjectType, []string{caseID, orderID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	signatureAsBytes, err := ctx.GetStub().GetState(signatureKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if signatureAsBytes == nil {
+		return nil, nil
+	}
+
+	orderSignature := new(OrderSignature)
+	err = json.Unmarshal(signatureAsBytes, orderSignature)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal order signature. %s", err.Error())
+	}
+	return orderSignature, nil
+}
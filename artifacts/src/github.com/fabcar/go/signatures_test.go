@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority issuing judge certificates in tests
+type testCA struct {
+	t      *testing.T
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	pem    string
+	serial int64
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{t: t, cert: cert, key: key, pem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), serial: 1}
+}
+
+// issue returns a PEM certificate for commonName signed by the CA, with its key
+func (ca *testCA) issue(commonName string) (string, *ecdsa.PrivateKey, *big.Int) {
+	ca.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), key, template.SerialNumber
+}
+
+// crl returns a PEM CRL of the CA revoking the given serial numbers an hour ago
+func (ca *testCA) crl(serials ...*big.Int) string {
+	ca.t.Helper()
+	var revoked []pkix.RevokedCertificate
+	for _, serial := range serials {
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: time.Now().Add(-time.Hour)})
+	}
+	der, err := ca.cert.CreateCRL(rand.Reader, ca.key, revoked, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+}
+
+func signHash(t *testing.T, key *ecdsa.PrivateKey, hashHex string) string {
+	t.Helper()
+	digest, err := hex.DecodeString(hashHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+// approveTrustAnchors publishes the trust anchors of Org1MSP, approved by Org2MSP
+func approveTrustAnchors(e *testEnv, anchors map[string]interface{}) {
+	e.t.Helper()
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("ProposeMSPTrustAnchors", toJSON(e.t, anchors))
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustInvoke("ApproveMSPTrustAnchors", "Org1MSP")
+}
+
+func TestMSPTrustAnchors(t *testing.T) {
+	e := newTestEnv(t)
+	ca := newTestCA(t, "ca.org1")
+	other := newTestCA(t, "ca.other")
+	leaf, _, _ := ca.issue("j")
+
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("ProposeMSPTrustAnchors", toJSON(t, map[string]interface{}{"rootCerts": []string{ca.pem}}))
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("ProposeMSPTrustAnchors", `{"rootCerts":[]}`)
+	e.mustFail("ProposeMSPTrustAnchors", toJSON(t, map[string]interface{}{"rootCerts": []string{leaf}}))
+	e.mustFail("ProposeMSPTrustAnchors", toJSON(t, map[string]interface{}{"rootCerts": []string{ca.pem}, "crls": []string{other.crl()}}))
+	e.mustInvoke("ProposeMSPTrustAnchors", toJSON(t, map[string]interface{}{"rootCerts": []string{ca.pem}, "crls": []string{ca.crl()}}))
+
+	// A proposal only takes effect once another organization approves it
+	e.mustFail("QueryMSPTrustAnchors", "Org1MSP")
+	e.mustFail("ApproveMSPTrustAnchors", "Org1MSP")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("ApproveMSPTrustAnchors", "Org3MSP")
+	e.mustInvoke("ApproveMSPTrustAnchors", "Org1MSP")
+	e.mustFail("ApproveMSPTrustAnchors", "Org1MSP")
+
+	anchors := new(MSPTrustAnchors)
+	err := json.Unmarshal([]byte(e.mustInvoke("QueryMSPTrustAnchors", "Org1MSP")), anchors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anchors.MSPID != "Org1MSP" || anchors.UpdatedBy != "admin1" || anchors.ApprovedBy != "admin2" || anchors.ApprovedMSP != "Org2MSP" || len(anchors.CRLs) != 1 {
+		t.Fatalf("unexpected trust anchors %+v", anchors)
+	}
+
+	// Later changes need both organizations to endorse
+	anchorsKey, err := e.stub.CreateCompositeKey(mspTrustAnchorsObjectType, []string{"Org1MSP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orgs := endorsingOrgs(e, anchorsKey); len(orgs) != 2 || orgs[0] != "Org1MSP" || orgs[1] != "Org2MSP" {
+		t.Fatalf("unexpected trust anchor endorsers %v", orgs)
+	}
+}
+
+func TestSignJudgment(t *testing.T) {
+	e := newOrderEnv(t)
+	ca := newTestCA(t, "ca.org1")
+	certificate, key, _ := ca.issue("j")
+
+	e.as("Org1MSP", "j", "judge")
+	order := decodeOrder(t, e.mustInvoke("IssueOrder", "C1", orderJSON("judgment", "")))
+	signature := signHash(t, key, order.TextHash)
+
+	// Signatures cannot be verified before the trust anchors of the judge's organization are approved
+	e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, signature, certificate)
+	approveTrustAnchors(e, map[string]interface{}{"rootCerts": []string{ca.pem}})
+
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, signature, certificate)
+
+	e.as("Org1MSP", "j", "judge")
+	e.mustFail("SignJudgment", "C1", "tx9999", order.TextHash, signature, certificate)
+	e.mustFail("SignJudgment", "C1", order.OrderID, strings.Repeat("cd", 32), signature, certificate)
+	e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, signHash(t, key, strings.Repeat("cd", 32)), certificate)
+	e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, "not base64", certificate)
+
+	// The certificate must belong to the issuing judge and chain to its organization
+	kimCertificate, kimKey, _ := ca.issue("k")
+	e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, signHash(t, kimKey, order.TextHash), kimCertificate)
+	forgedCertificate, forgedKey, _ := newTestCA(t, "ca.org1").issue("j")
+	e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, signHash(t, forgedKey, order.TextHash), forgedCertificate)
+
+	stored := new(OrderSignature)
+	err := json.Unmarshal([]byte(e.mustInvoke("SignJudgment", "C1", order.OrderID, strings.ToUpper(order.TextHash), signature, certificate)), stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.JudgeID != "j" || stored.MSPID != "Org1MSP" || stored.SubmittedBy != "j" || stored.Hash != order.TextHash {
+		t.Fatalf("unexpected signature %+v", stored)
+	}
+	e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, signature, certificate)
+
+	if signed := decodeOrder(t, e.mustInvoke("QueryOrder", "C1", order.OrderID)); signed.Signature == nil || signed.Signature.TxID != stored.TxID {
+		t.Fatalf("unexpected order %+v", signed)
+	}
+}
+
+func TestSignJudgmentRevokedCertificate(t *testing.T) {
+	e := newOrderEnv(t)
+	ca := newTestCA(t, "ca.org1")
+	certificate, key, serial := ca.issue("j")
+	approveTrustAnchors(e, map[string]interface{}{"rootCerts": []string{ca.pem}, "crls": []string{ca.crl(serial)}})
+
+	e.as("Org1MSP", "j", "judge")
+	order := decodeOrder(t, e.mustInvoke("IssueOrder", "C1", orderJSON("judgment", "")))
+	if msg := e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, signHash(t, key, order.TextHash), certificate); !strings.Contains(msg, "revoked") {
+		t.Fatal(msg)
+	}
+
+	// An approver may submit the signature of a newly issued certificate on the judge's behalf
+	renewed, renewedKey, _ := ca.issue("j")
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("SignJudgment", "C1", order.OrderID, order.TextHash, signHash(t, renewedKey, order.TextHash), renewed)
+}
+
+func TestSignJudgmentOfUnassignedJudge(t *testing.T) {
+	e := newOrderEnv(t)
+	ca := newTestCA(t, "ca.org1")
+	certificate, key, _ := ca.issue("j")
+	approveTrustAnchors(e, map[string]interface{}{"rootCerts": []string{ca.pem}})
+
+	e.as("Org1MSP", "j", "judge")
+	order := decodeOrder(t, e.mustInvoke("IssueOrder", "C1", orderJSON("judgment", "")))
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("UnassignJudge", "C1", "j", "retired")
+
+	e.as("Org1MSP", "j", "judge")
+	if msg := e.mustFail("SignJudgment", "C1", order.OrderID, order.TextHash, signHash(t, key, order.TextHash), certificate); !strings.Contains(msg, "no longer assigned") {
+		t.Fatal(msg)
+	}
+}
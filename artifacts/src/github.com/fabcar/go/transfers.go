@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	caseTransferObjectType = "caseTransfer"
+
+	CaseTransferPending  = "PENDING"
+	CaseTransferAccepted = "ACCEPTED"
+	CaseTransferRejected = "REJECTED"
+)
+
+// CaseTransfer is a change of venue of a case to another court. It is initiated by the organization
+// owning the case and only takes effect once the organization owning the receiving court accepts it.
+type CaseTransfer struct {
+	TransferID  string `json:"transferID"`
+	CaseID      string `json:"caseID"`
+	FromCourtID string `json:"fromCourtID"`
+	ToCourtID   string `json:"toCourtID"`
+	FromMSP     string `json:"fromMSP"`
+	ToMSP       string `json:"toMSP"`
+	ToZip       string `json:"toZip,omitempty" metadata:"toZip,optional"`
+	Reason      string `json:"reason"`
+	Status      string `json:"status"`
+	InitiatedBy string `json:"initiatedBy"`
+	InitiatedAt string `json:"initiatedAt"`
+	DecidedBy   string `json:"decidedBy,omitempty" metadata:"decidedBy,optional"`
+	DecidedAt   string `json:"decidedAt,omitempty" metadata:"decidedAt,optional"`
+	Note        string `json:"note,omitempty" metadata:"note,optional"`
+}
+
+// InitiateCaseTransfer proposes moving a case to another court. toZip may be empty to use the first
+// zip code served by the receiving court. Only one transfer of a case can be pending at a time.
+func (s *SmartContract) InitiateCaseTransfer(ctx contractapi.TransactionContextInterface, caseID string, toCourtID string, toZip string, reason string) (string, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return "", err
+	}
+	if len(reason) == 0 {
+		return "", fmt.Errorf("Please pass a reason for the transfer")
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return "", err
+	}
+	if len(legalRecord.CourtID) == 0 {
+		return "", fmt.Errorf("%s does not reference a registered court", caseID)
+	}
+	err = requireCourtOwner(ctx, legalRecord.OwnerMSP)
+	if err != nil {
+		return "", err
+	}
+	if legalRecord.CourtID == toCourtID {
+		return "", fmt.Errorf("%s is already before court %s", caseID, toCourtID)
+	}
+
+	toCourt, err := getCourt(ctx, toCourtID)
+	if err != nil {
+		return "", err
+	}
+	if len(toZip) > 0 && !toCourt.servesZip(toZip) {
+		return "", fmt.Errorf("Court %s does not serve zip code %s", toCourt.ID, toZip)
+	}
+
+	transfers, err := getCaseTransfers(ctx, caseID)
+	if err != nil {
+		return "", err
+	}
+	for _, transfer := range transfers {
+		if transfer.Status == CaseTransferPending {
+			return "", fmt.Errorf("Transfer %s of %s is still pending", transfer.TransferID, caseID)
+		}
+	}
+
+	initiatedBy, err := getClientName(ctx)
+	if err != nil {
+		return "", err
+	}
+	initiatedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	transfer := &CaseTransfer{
+		TransferID:  ctx.GetStub().GetTxID(),
+		CaseID:      caseID,
+		FromCourtID: legalRecord.CourtID,
+		ToCourtID:   toCourt.ID,
+		FromMSP:     legalRecord.OwnerMSP,
+		ToMSP:       toCourt.OwnerMSP,
+		ToZip:       toZip,
+		Reason:      reason,
+		Status:      CaseTransferPending,
+		InitiatedBy: initiatedBy,
+		InitiatedAt: initiatedAt,
+	}
+	transferAsBytes, err := putCaseTransfer(ctx, transfer)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.GetStub().SetEvent("InitiateCaseTransfer", transferAsBytes)
+
+	return transfer.TransferID, nil
+}
+
+// AcceptCaseTransfer moves a case to the receiving court of a pending transfer. It must be submitted
+// by an approver of the receiving organization and endorsed by both organizations: the write to the
+// legal record is checked against its current policy and the write to the receiving organization's
+// endorsement key against that organization. Court, ownership, private collection and endorsement
+// policy change in the same transaction, and judges of the old court are unassigned. The private
+// details of a non-public case must be passed in the transient map; they are checked against the
+// hash on the record and written to the receiving organization's collection.
+func (s *SmartContract) AcceptCaseTransfer(ctx contractapi.TransactionContextInterface, caseID string, transferID string) error {
+	transfer, legalRecord, err := getPendingCaseTransfer(ctx, caseID, transferID)
+	if err != nil {
+		return err
+	}
+	toCourt, err := getCourt(ctx, transfer.ToCourtID)
+	if err != nil {
+		return err
+	}
+	if toCourt.OwnerMSP != transfer.ToMSP {
+		return fmt.Errorf("Court %s has changed owner since the transfer was initiated", toCourt.ID)
+	}
+	if legalRecord.CourtID != transfer.FromCourtID || legalRecord.OwnerMSP != transfer.FromMSP {
+		return fmt.Errorf("%s has moved since the transfer was initiated", caseID)
+	}
+
+	toZip := transfer.ToZip
+	if len(toZip) == 0 {
+		toZip = toCourt.ZipCodes[0]
+	}
+	err = moveLegalRecordToCourt(ctx, legalRecord, toCourt, toZip, fmt.Sprintf("transferred to court %s", toCourt.ID))
+	if err != nil {
+		return err
+	}
+	_, err = putLegalRecord(ctx, legalRecord)
+	if err != nil {
+		return err
+	}
+	err = setRecordEndorsementPolicy(ctx, legalRecord)
+	if err != nil {
+		return err
+	}
+
+	err = decideCaseTransfer(ctx, transfer, CaseTransferAccepted, "")
+	if err != nil {
+		return err
+	}
+	_, err = appendDocketEntry(ctx, caseID, &DocketEntry{
+		Type:        DocketNotice,
+		Description: fmt.Sprintf("Case transferred from court %s to court %s: %s", transfer.FromCourtID, transfer.ToCourtID, transfer.Reason),
+		DocumentRef: transfer.TransferID,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("%s transferred from court %s to court %s", caseID, transfer.FromCourtID, transfer.ToCourtID)
+	transferAsBytes, err := json.Marshal(transfer)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal case transfer. %s", err.Error())
+	}
+	return ctx.GetStub().SetEvent("AcceptCaseTransfer", transferAsBytes)
+}
+
+// RejectCaseTransfer declines a pending transfer on behalf of the receiving organization
+func (s *SmartContract) RejectCaseTransfer(ctx contractapi.TransactionContextInterface, caseID string, transferID string, reason string) error {
+	if len(reason) == 0 {
+		return fmt.Errorf("Please pass a reason for rejecting the transfer")
+	}
+
+	transfer, _, err := getPendingCaseTransfer(ctx, caseID, transferID)
+	if err != nil {
+		return err
+	}
+	err = decideCaseTransfer(ctx, transfer, CaseTransferRejected, reason)
+	if err != nil {
+		return err
+	}
+
+	transferAsBytes, err := json.Marshal(transfer)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal case transfer. %s", err.Error())
+	}
+	return ctx.GetStub().SetEvent("RejectCaseTransfer", transferAsBytes)
+}
+
+// GetCaseTransfers returns all transfers of a case, including rejected ones, to callers who may read the case
+func (s *SmartContract) GetCaseTransfers(ctx contractapi.TransactionContextInterface, caseID string) ([]*CaseTransfer, error) {
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = requireCaseReader(ctx, legalRecord)
+	if err != nil {
+		return nil, err
+	}
+	return getCaseTransfers(ctx, caseID)
+}
+
+// getPendingCaseTransfer loads a pending transfer and its legal record after checking the caller is
+// an approver of the receiving organization
+func getPendingCaseTransfer(ctx contractapi.TransactionContextInterface, caseID string, transferID string) (*CaseTransfer, *LegalRecord, error) {
+	_, err := requireRole(ctx, "approver")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transferKey, err := ctx.GetStub().CreateCompositeKey(caseTransferObjectType, []string{caseID, transferID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+	transferAsBytes, err := ctx.GetStub().GetState(transferKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read from world state. %s", err.Error())
+	}
+	if transferAsBytes == nil {
+		return nil, nil, fmt.Errorf("Transfer %s does not exist for %s", transferID, caseID)
+	}
+
+	transfer := new(CaseTransfer)
+	err = json.Unmarshal(transferAsBytes, transfer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to unmarshal case transfer. %s", err.Error())
+	}
+	if transfer.Status != CaseTransferPending {
+		return nil, nil, fmt.Errorf("Transfer %s has already been decided", transferID)
+	}
+	err = requireCourtOwner(ctx, transfer.ToMSP)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	legalRecord, err := getLegalRecord(ctx, caseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return transfer, legalRecord, nil
+}
+
+func decideCaseTransfer(ctx contractapi.TransactionContextInterface, transfer *CaseTransfer, status string, note string) error {
+	decidedBy, err := getClientName(ctx)
+	if err != nil {
+		return err
+	}
+	decidedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	transfer.Status = status
+	transfer.DecidedBy = decidedBy
+	transfer.DecidedAt = decidedAt
+	transfer.Note = note
+	_, err = putCaseTransfer(ctx, transfer)
+	return err
+}
+
+func getCaseTransfers(ctx contractapi.TransactionContextInterface, caseID string) ([]*CaseTransfer, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(caseTransferObjectType, []string{caseID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state iterator. %s", err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var transfers []*CaseTransfer
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get next item from iterator. %s", err.Error())
+		}
+
+		var transfer CaseTransfer
+		err = json.Unmarshal(queryResponse.Value, &transfer)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal case transfer. %s", err.Error())
+		}
+		transfers = append(transfers, &transfer)
+	}
+
+	return transfers, nil
+}
+
+func putCaseTransfer(ctx contractapi.TransactionContextInterface, transfer *CaseTransfer) ([]byte, error) {
+	err := requireNoLegalHold(ctx, transfer.CaseID)
+	if err != nil {
+		return nil, err
+	}
+	transferKey, err := ctx.GetStub().CreateCompositeKey(caseTransferObjectType, []string{transfer.CaseID, transfer.TransferID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create composite key. %s", err.Error())
+	}
+
+	transferAsBytes, err := json.Marshal(transfer)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal case transfer. %s", err.Error())
+	}
+
+	err = ctx.GetStub().PutState(transferKey, transferAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put case transfer. %s", err.Error())
+	}
+	return transferAsBytes, nil
+}
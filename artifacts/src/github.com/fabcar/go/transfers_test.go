@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func newTransferEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.as("Org1MSP", "admin1", "approver")
+	e.setPrivateDetails(map[string]string{"description": "Contract dispute"})
+	e.mustInvoke("CreateLegalRecord", toJSON(t, map[string]interface{}{"caseID": "C1", "courtID": "CT1", "judges": []string{"j"}}))
+	return e
+}
+
+func getStoredLegalRecord(e *testEnv, caseID string) *LegalRecord {
+	e.t.Helper()
+	legalRecord := new(LegalRecord)
+	err := json.Unmarshal(e.stub.State[caseID], legalRecord)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return legalRecord
+}
+
+func queryCaseTransfers(e *testEnv, caseID string) []*CaseTransfer {
+	e.t.Helper()
+	var transfers []*CaseTransfer
+	err := json.Unmarshal([]byte(e.mustInvoke("GetCaseTransfers", caseID)), &transfers)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return transfers
+}
+
+func TestInitiateCaseTransfer(t *testing.T) {
+	e := newTransferEnv(t)
+
+	e.mustFail("InitiateCaseTransfer", "C1", "CT1", "", "venue")
+	e.mustFail("InitiateCaseTransfer", "C1", "CT2", "99999", "venue")
+	e.mustFail("InitiateCaseTransfer", "C1", "CT2", "", "")
+	e.mustFail("InitiateCaseTransfer", "C1", "CT404", "", "venue")
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("InitiateCaseTransfer", "C1", "CT2", "", "venue")
+
+	e.as("Org1MSP", "admin1", "approver")
+	transferID := e.mustInvoke("InitiateCaseTransfer", "C1", "CT2", "20001", "venue")
+	e.mustFail("InitiateCaseTransfer", "C1", "CT2", "", "venue")
+	// The initiating organization cannot accept its own transfer
+	e.mustFail("AcceptCaseTransfer", "C1", transferID)
+
+	transfers := queryCaseTransfers(e, "C1")
+	if len(transfers) != 1 || transfers[0].Status != CaseTransferPending || transfers[0].FromMSP != "Org1MSP" || transfers[0].ToMSP != "Org2MSP" {
+		t.Fatalf("unexpected transfers %+v", transfers)
+	}
+
+	e.as("Org1MSP", "bob", "client")
+	e.mustFail("GetCaseTransfers", "C1")
+}
+
+func TestAcceptCaseTransfer(t *testing.T) {
+	e := newTransferEnv(t)
+	transferID := e.mustInvoke("InitiateCaseTransfer", "C1", "CT2", "", "venue")
+
+	// The private details must be passed unchanged to be moved to the receiving organization
+	e.as("Org2MSP", "admin2", "approver")
+	e.setPrivateDetails(map[string]string{"description": "Forged"})
+	e.mustFail("AcceptCaseTransfer", "C1", transferID)
+	e.setPrivateDetails(map[string]string{"description": "Contract dispute"})
+	e.mustInvoke("AcceptCaseTransfer", "C1", transferID)
+	e.mustFail("AcceptCaseTransfer", "C1", transferID)
+
+	legalRecord := getStoredLegalRecord(e, "C1")
+	if legalRecord.CourtID != "CT2" || legalRecord.CourtZip != "20001" || legalRecord.OwnerMSP != "Org2MSP" {
+		t.Fatalf("record was not moved to CT2: %+v", legalRecord)
+	}
+	if len(legalRecord.Judges) != 0 {
+		t.Fatalf("judges of the previous court are still assigned: %v", legalRecord.Judges)
+	}
+	if legalRecord.PrivateCollection != "confidentialRecordsOrg2MSP" || len(e.stub.PvtState["confidentialRecordsOrg2MSP"]["C1"]) == 0 {
+		t.Fatalf("private details were not moved: %s", legalRecord.PrivateCollection)
+	}
+
+	transfers := queryCaseTransfers(e, "C1")
+	if len(transfers) != 1 || transfers[0].Status != CaseTransferAccepted || transfers[0].DecidedBy != "admin2" {
+		t.Fatalf("unexpected transfers %+v", transfers)
+	}
+
+	// Only the receiving organization may change the record now
+	e.mustInvoke("UpdateLegalRecord", "C1", `{"status":"OPEN"}`)
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustFail("UpdateLegalRecord", "C1", `{"status":"CLOSED"}`)
+}
+
+func TestRejectCaseTransfer(t *testing.T) {
+	e := newTransferEnv(t)
+	transferID := e.mustInvoke("InitiateCaseTransfer", "C1", "CT2", "", "venue")
+	e.mustFail("RejectCaseTransfer", "C1", transferID, "no capacity")
+
+	e.as("Org2MSP", "admin2", "approver")
+	e.mustFail("RejectCaseTransfer", "C1", transferID, "")
+	e.mustInvoke("RejectCaseTransfer", "C1", transferID, "no capacity")
+	e.mustFail("AcceptCaseTransfer", "C1", transferID)
+
+	legalRecord := getStoredLegalRecord(e, "C1")
+	if legalRecord.CourtID != "CT1" || legalRecord.OwnerMSP != "Org1MSP" || !containsString(legalRecord.Judges, "j") {
+		t.Fatalf("rejected transfer changed the record: %+v", legalRecord)
+	}
+
+	// A rejected transfer no longer blocks a new one
+	e.as("Org1MSP", "admin1", "approver")
+	e.mustInvoke("InitiateCaseTransfer", "C1", "CT2", "", "venue")
+	if transfers := queryCaseTransfers(e, "C1"); len(transfers) != 2 {
+		t.Fatalf("unexpected transfers %+v", transfers)
+	}
+}
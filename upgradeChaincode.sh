@@ -52,6 +52,7 @@ VERSION="11"
 SEQUENCE=9
 CC_SRC_PATH="./artifacts/src/github.com/fabcar/go"
 CC_NAME="fabcar"
+CC_COLLECTIONS_CONFIG="${CC_SRC_PATH}/collections_config.json"
 
 packageChaincode() {
     rm -rf ${CC_NAME}.tar.gz
@@ -97,6 +98,7 @@ approveForMyOrg1() {
     setGlobalsForPeer0Org1
     # set -x
     peer lifecycle chaincode approveformyorg -o localhost:7050 \
+        --collections-config ${CC_COLLECTIONS_CONFIG} \
         --ordererTLSHostnameOverride orderer.example.com --tls \
         --signature-policy "OR('Org1MSP.member','Org2MSP.member', 'Org3MSP.member')" \
         --cafile $ORDERER_CA --channelID $CHANNEL_NAME --name ${CC_NAME} --version ${VERSION} \
@@ -120,6 +122,7 @@ approveForMyOrg1() {
 checkCommitReadyness() {
     setGlobalsForPeer0Org1
     peer lifecycle chaincode checkcommitreadiness \
+        --collections-config ${CC_COLLECTIONS_CONFIG} \
         --channelID $CHANNEL_NAME --name ${CC_NAME} --version ${VERSION} \
         --signature-policy "OR('Org1MSP.member','Org2MSP.member', 'Org3MSP.member')" \
         --sequence ${SEQUENCE} --output json
@@ -132,6 +135,7 @@ approveForMyOrg2() {
     setGlobalsForPeer0Org2
 
     peer lifecycle chaincode approveformyorg -o localhost:7050 \
+        --collections-config ${CC_COLLECTIONS_CONFIG} \
         --ordererTLSHostnameOverride orderer.example.com --tls $CORE_PEER_TLS_ENABLED \
         --signature-policy "OR('Org1MSP.member','Org2MSP.member', 'Org3MSP.member')" \
         --cafile $ORDERER_CA --channelID $CHANNEL_NAME --name ${CC_NAME} \
@@ -148,6 +152,7 @@ checkCommitReadyness() {
 
     setGlobalsForPeer0Org2
     peer lifecycle chaincode checkcommitreadiness --channelID $CHANNEL_NAME \
+        --collections-config ${CC_COLLECTIONS_CONFIG} \
         --peerAddresses localhost:9051 --tlsRootCertFiles $PEER0_ORG2_CA \
         --signature-policy "OR('Org1MSP.member','Org2MSP.member', 'Org3MSP.member')" \
         --name ${CC_NAME} --version ${VERSION} --sequence ${SEQUENCE} --output json
@@ -160,6 +165,7 @@ approveForMyOrg3() {
     setGlobalsForPeer0Org3
 
     peer lifecycle chaincode approveformyorg -o localhost:7050 \
+        --collections-config ${CC_COLLECTIONS_CONFIG} \
         --ordererTLSHostnameOverride orderer.example.com --tls $CORE_PEER_TLS_ENABLED \
         --signature-policy "OR('Org1MSP.member','Org2MSP.member', 'Org3MSP.member')" \
         --cafile $ORDERER_CA --channelID $CHANNEL_NAME --name ${CC_NAME} \
@@ -176,6 +182,7 @@ checkCommitReadyness() {
 
     setGlobalsForPeer0Org3
     peer lifecycle chaincode checkcommitreadiness --channelID $CHANNEL_NAME \
+        --collections-config ${CC_COLLECTIONS_CONFIG} \
         --peerAddresses localhost:11051 --tlsRootCertFiles $PEER0_ORG3_CA \
         --signature-policy "OR('Org1MSP.member','Org2MSP.member', 'Org3MSP.member')" \
         --name ${CC_NAME} --version ${VERSION} --sequence ${SEQUENCE} --output json
@@ -187,6 +194,7 @@ checkCommitReadyness() {
 commitChaincodeDefination() {
     setGlobalsForPeer0Org1
     peer lifecycle chaincode commit -o localhost:7050 --ordererTLSHostnameOverride orderer.example.com \
+        --collections-config ${CC_COLLECTIONS_CONFIG} \
         --tls $CORE_PEER_TLS_ENABLED --cafile $ORDERER_CA \
         --signature-policy "OR('Org1MSP.member','Org2MSP.member', 'Org3MSP.member')" \
         --channelID $CHANNEL_NAME --name ${CC_NAME} \